@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// AdminHandler handles web administrator account requests
+type AdminHandler struct {
+	service *services.AdminService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(service *services.AdminService) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+	}
+}
+
+// ListAdmins lists all web administrators
+func (h *AdminHandler) ListAdmins(c *gin.Context) {
+	admins, err := h.service.ListAdmins()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, admins)
+}
+
+// CreateAdmin adds a web administrator
+func (h *AdminHandler) CreateAdmin(c *gin.Context) {
+	var req types.CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.CreateAdmin(&req); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseCreated(c, gin.H{
+		"username": req.Username,
+	})
+}
+
+// DeleteAdmin removes a web administrator
+func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
+	username := c.Param("username")
+	currentAdmin, _ := middlewares.GetUsernameFromContext(c)
+
+	if err := h.service.DeleteAdmin(username, currentAdmin); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Existing sessions of the removed admin end immediately
+	middlewares.InvalidateUserCache(username)
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Admin deleted successfully")
+}
+
+// ChangeAdminPassword changes a web administrator's password
+func (h *AdminHandler) ChangeAdminPassword(c *gin.Context) {
+	username := c.Param("username")
+	currentAdmin, _ := middlewares.GetUsernameFromContext(c)
+
+	// Only full admins may change the password of another admin
+	if username != currentAdmin && !hasAdminRole(c, types.AdminRoleFull) {
+		utils.ResponseForbidden(c, "Insufficient admin permissions")
+		return
+	}
+
+	var req types.ChangeAdminPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Admins changing their own password confirm the current one, which counts against the login throttle
+	ownPassword := username == currentAdmin
+	if ownPassword && rejectThrottledLogin(c, username) {
+		return
+	}
+
+	err := h.service.ChangeAdminPassword(username, currentAdmin, &req)
+	if ownPassword {
+		var unauthorizedErr *utils.UnauthorizedError
+		finishPasswordCheck(c, username, !errors.As(err, &unauthorizedErr))
+	}
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Sessions signed in with the old password end
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Password changed successfully")
+}
+
+// UpdateAdminRoles replaces the roles of a web administrator
+func (h *AdminHandler) UpdateAdminRoles(c *gin.Context) {
+	username := c.Param("username")
+	currentAdmin, _ := middlewares.GetUsernameFromContext(c)
+
+	var req types.UpdateAdminRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.UpdateAdminRoles(username, currentAdmin, req.Roles); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Sessions started with the old roles end, the admin signs in again with the new ones
+	middlewares.InvalidateUserCache(username)
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Admin roles updated successfully")
+}
+
+// GrantSambaAdmin promotes an existing Samba user to admin or changes their admin roles
+func (h *AdminHandler) GrantSambaAdmin(c *gin.Context) {
+	username := c.Param("username")
+	currentAdmin, _ := middlewares.GetUsernameFromContext(c)
+
+	var req types.UpdateAdminRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.GrantSambaAdmin(username, currentAdmin, req.Roles); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	middlewares.InvalidateUserCache(username)
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Admin roles granted successfully")
+}
+
+// RevokeSambaAdmin turns a promoted Samba user back into a regular user
+// Their existing sessions are signed out
+func (h *AdminHandler) RevokeSambaAdmin(c *gin.Context) {
+	username := c.Param("username")
+	currentAdmin, _ := middlewares.GetUsernameFromContext(c)
+
+	if err := h.service.RevokeSambaAdmin(username, currentAdmin); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	middlewares.InvalidateUserCache(username)
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Admin roles revoked successfully")
+}
+
+// hasAdminRole reports whether the current admin holds a role
+func hasAdminRole(c *gin.Context, role string) bool {
+	for _, adminRole := range middlewares.GetAdminRolesFromContext(c) {
+		if adminRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectAdminTarget answers with 403 when an admin without the full admin role acts on an admin account
+// A user manager could otherwise take over an admin through its password, reset token or sessions
+func rejectAdminTarget(c *gin.Context, username string) bool {
+	_, localAdmin := services.GetAdminRoles(username)
+	_, sambaAdmin := services.GetSambaAdminRoles(username)
+	if (localAdmin || sambaAdmin) && !hasAdminRole(c, types.AdminRoleFull) {
+		utils.ResponseForbidden(c, "Insufficient admin permissions")
+		return true
+	}
+	return false
+}
@@ -1,14 +1,21 @@
 package handlers
 
 import (
-	"os/exec"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
 	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/itsHenry35/SambaManager/api/middlewares"
 	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/services"
 	"github.com/itsHenry35/SambaManager/types"
 	"github.com/itsHenry35/SambaManager/utils"
 )
@@ -21,14 +28,230 @@ type LoginRequest struct {
 
 // LoginResponse represents login response data
 type LoginResponse struct {
-	Token     string         `json:"token"`
-	Username  string         `json:"username"`
-	Role      types.UserRole `json:"role"`
-	ExpiresAt int64          `json:"expires_at"`
+	Token                  string         `json:"token,omitempty"` // Short-lived access token
+	RefreshToken           string         `json:"refresh_token,omitempty"`
+	Username               string         `json:"username"`
+	Role                   types.UserRole `json:"role,omitempty"`
+	Roles                  []string       `json:"roles,omitempty"` // Admin roles, used by the frontend to hide unavailable actions
+	ExpiresAt              int64          `json:"expires_at"`
+	RefreshExpiresAt       int64          `json:"refresh_expires_at,omitempty"`
+	PasswordChangeRequired bool           `json:"password_change_required,omitempty"`  // The token only allows PUT /api/user/password
+	TwoFactorSetupRequired bool           `json:"two_factor_setup_required,omitempty"` // The token only allows setting up two-factor authentication
+	TwoFactorRequired      bool           `json:"two_factor_required,omitempty"`       // No token yet, send a code with the challenge token to /api/login/two-factor
+	ChallengeToken         string         `json:"challenge_token,omitempty"`
+}
+
+// RefreshTokenRequest represents a request for a new access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+const restrictedTokenLifetime = 15 * time.Minute
+
+const (
+	clientCookie     = "session_client"
+	clientCookiePath = "/api"
+)
+
+// loginUsernamePattern allows alphanumeric, underscore, dash (1-32 chars)
+var loginUsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// clientSecret returns the browser's client secret from its cookie, or a new one if it has none
+// Refresh tokens only work together with it, and scripts can't read the HttpOnly cookie to take it along
+func clientSecret(c *gin.Context) (string, error) {
+	if secret, err := c.Cookie(clientCookie); err == nil {
+		if decoded, err := base64.RawURLEncoding.DecodeString(secret); err == nil && len(decoded) == 32 {
+			return secret, nil
+		}
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate client secret: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// setClientCookie stores the client secret in the browser for as long as a login can be refreshed
+func setClientCookie(c *gin.Context, secret string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     clientCookie,
+		Value:    secret,
+		Path:     clientCookiePath,
+		MaxAge:   int(config.GetRefreshTokenLifetime().Seconds()),
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// issueToken starts a session and signs an access token and refresh token for it
+// Tokens with a scope are short-lived, can't be refreshed and only allow the routes of their scope
+func issueToken(c *gin.Context, username string, role types.UserRole, adminRoles []string, scope string) (*LoginResponse, error) {
+	lifetime := config.GetRefreshTokenLifetime()
+	secret := ""
+	if scope != "" {
+		lifetime = restrictedTokenLifetime
+	} else {
+		var err error
+		if secret, err = clientSecret(c); err != nil {
+			return nil, err
+		}
+	}
+
+	session, refreshToken, err := services.CreateSession(username, c.ClientIP(), c.Request.UserAgent(), time.Now().Add(lifetime), secret)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := signAccessToken(session, role, adminRoles, scope)
+	if err != nil {
+		return nil, err
+	}
+	response.RefreshToken = refreshToken
+	if refreshToken != "" {
+		response.RefreshExpiresAt = session.ExpiresAt.Unix()
+		setClientCookie(c, secret)
+	}
+	response.PasswordChangeRequired = scope == middlewares.ScopePasswordChange
+	response.TwoFactorSetupRequired = scope == middlewares.ScopeTwoFactorSetup
+
+	return response, nil
+}
+
+// loginScope returns the scope of the token issued after a successful login
+// An expired password is changed first, then two-factor authentication required by the policy is set up
+func loginScope(username string, role types.UserRole, passwordChangeRequired bool) string {
+	if passwordChangeRequired {
+		return middlewares.ScopePasswordChange
+	}
+	if services.TwoFactorSetupRequired(username, role == types.RoleAdmin) {
+		return middlewares.ScopeTwoFactorSetup
+	}
+	return ""
+}
+
+// accountRole looks up the current role of an account, active is false for deleted or disabled users
+func accountRole(username string) (role types.UserRole, adminRoles []string, active bool) {
+	if adminRoles, ok := services.GetAdminRoles(username); ok {
+		return types.RoleAdmin, adminRoles, true
+	}
+	if _, active := services.GetUserStatus(username); !active {
+		return "", nil, false
+	}
+	if adminRoles, ok := services.GetSambaAdminRoles(username); ok {
+		return types.RoleAdmin, adminRoles, true
+	}
+	return types.RoleUser, nil, true
+}
+
+// signAccessToken signs a JWT for a session, valid no longer than the session itself
+func signAccessToken(session *types.Session, role types.UserRole, adminRoles []string, scope string) (*LoginResponse, error) {
+	expirationTime := time.Now().Add(config.GetAccessTokenLifetime())
+	if session.ExpiresAt.Before(expirationTime) {
+		expirationTime = session.ExpiresAt
+	}
+
+	claims := &middlewares.Claims{
+		Username: session.Username,
+		Role:     string(role),
+		Roles:    adminRoles,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.ID,
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(config.GetJWTSecret())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:     tokenString,
+		Username:  session.Username,
+		Role:      role,
+		Roles:     adminRoles,
+		ExpiresAt: expirationTime.Unix(),
+	}, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token and refresh token
+// The role is looked up again, so a refreshed token always reflects the current account state
+func RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	secret, _ := c.Cookie(clientCookie)
+	session, refreshToken, err := services.RefreshSession(req.RefreshToken, secret, c.ClientIP())
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	role, adminRoles, active := accountRole(session.Username)
+	message := ""
+	switch {
+	case !active:
+		// Deleted or disabled users can't renew their session
+		message = "Account is disabled or no longer exists"
+	case services.TwoFactorSetupRequired(session.Username, role == types.RoleAdmin):
+		// The policy changed since the login, signing in again leads through the setup
+		message = "Two-factor authentication setup required, please sign in again"
+	}
+	if message != "" {
+		if err := services.RevokeSession(session.ID, session.Username); err != nil {
+			log.Printf("Failed to revoke session of %s: %v", session.Username, err)
+		}
+		utils.ResponseUnauthorized(c, message)
+		return
+	}
+
+	response, err := signAccessToken(session, role, adminRoles, "")
+	if err != nil {
+		utils.ResponseInternalServerError(c, "Failed to generate token")
+		return
+	}
+	response.RefreshToken = refreshToken
+	response.RefreshExpiresAt = session.ExpiresAt.Unix()
+	setClientCookie(c, secret)
+
+	utils.ResponseOK(c, response)
+}
+
+// rejectThrottledLogin answers attempts during a backoff delay or lockout, before any credential check runs
+// Allowed attempts are reserved and must end with RecordLoginFailure, RecordLoginSuccess or ReleaseLoginAttempt
+func rejectThrottledLogin(c *gin.Context, username string) bool {
+	wait, allowed := services.CheckLoginAllowed(username, c.ClientIP())
+	if allowed {
+		return false
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	utils.ResponseTooManyRequests(c, fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds))
+	return true
+}
+
+// finishPasswordCheck ends an attempt reserved by rejectThrottledLogin for a current password check
+// Failures are throttled and audited like failed logins
+func finishPasswordCheck(c *gin.Context, username string, verified bool) {
+	if verified {
+		services.RecordLoginSuccess(username, c.ClientIP())
+		return
+	}
+	services.RecordLoginFailure(username, c.ClientIP(), c.Request.UserAgent(), services.LoginFailureInvalidCurrentPassword)
 }
 
 // Login authenticates a user and returns a JWT token
 // Supports both admin (from config) and regular users (via Samba authentication)
+// Accounts with two-factor authentication get a challenge token for LoginTwoFactor instead
 func Login(c *gin.Context) {
 	var credentials LoginRequest
 
@@ -38,63 +261,121 @@ func Login(c *gin.Context) {
 	}
 
 	// SECURITY: Validate username format to prevent command injection
-	// Only allow alphanumeric, underscore, dash (1-32 chars)
-	usernameRegex := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
-	if !usernameRegex.MatchString(credentials.Username) {
+	if !loginUsernamePattern.MatchString(credentials.Username) {
 		utils.ResponseUnauthorized(c, "Illegal request")
 		return
 	}
 
+	if rejectThrottledLogin(c, credentials.Username) {
+		return
+	}
+	ip := c.ClientIP()
+
 	var role types.UserRole
+	var adminRoles []string
+	var passwordChangeRequired bool
 
 	// Check if admin login
-	if credentials.Username == config.AppConfig.Admin.Username &&
-		credentials.Password == config.AppConfig.Admin.Password {
+	if services.VerifyAdminCredentials(credentials.Username, credentials.Password) {
 		role = types.RoleAdmin
+		adminRoles, _ = services.GetAdminRoles(credentials.Username)
 	} else {
-		// Try to authenticate as regular user via Samba
-		// First check if user exists in Samba (username already validated above)
-		cmd := exec.Command("pdbedit", "-L", "-u", credentials.Username)
-		if _, err := cmd.CombinedOutput(); err != nil {
+		// Disabled accounts can't log in to the web UI either
+		// The answer is the same as for a wrong password, so accounts and their status can't be probed
+		if exists, active := services.GetUserStatus(credentials.Username); exists && !active {
+			services.RecordLoginFailure(credentials.Username, ip, c.Request.UserAgent(), services.LoginFailureAccountDisabled)
 			utils.ResponseUnauthorized(c, "Invalid credentials")
 			return
 		}
 
-		// Verify password by attempting to authenticate with smbclient
-		// SECURITY: username is validated with regex above
-		// password is passed as part of -U parameter in the format "username%password"
-		// This is safe because smbclient handles special characters in passwords correctly
-		cmd = exec.Command("smbclient", "-L", "localhost", "-U", credentials.Username+"%"+credentials.Password)
-		if err := cmd.Run(); err != nil {
+		// Try to authenticate as regular user via Samba (username already validated above)
+		valid, expired := services.CheckSambaPassword(credentials.Username, credentials.Password)
+		if !valid {
+			services.RecordLoginFailure(credentials.Username, ip, c.Request.UserAgent(), services.LoginFailureInvalidCredentials)
 			utils.ResponseUnauthorized(c, "Invalid credentials")
 			return
 		}
 
 		role = types.RoleUser
+		// Samba users promoted to admin log in with their Samba password
+		if roles, ok := services.GetSambaAdminRoles(credentials.Username); ok {
+			role = types.RoleAdmin
+			adminRoles = roles
+		}
+		// Expired passwords get a token that can only be used to set a new password
+		passwordChangeRequired = expired || services.PasswordChangeRequired(credentials.Username)
 	}
 
-	// Generate JWT token
-	expirationTime := time.Now().Add(24 * 30 * time.Hour)
-	claims := &middlewares.Claims{
-		Username: credentials.Username,
-		Role:     string(role),
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	finishLogin(c, credentials.Username, role, adminRoles, passwordChangeRequired)
+}
+
+// finishLogin answers a login whose first factor was verified and ends its reserved attempt
+// Accounts with two-factor authentication get a challenge token for LoginTwoFactor instead of a token
+func finishLogin(c *gin.Context, username string, role types.UserRole, adminRoles []string, passwordChangeRequired bool) {
+	// Failed logins are only forgiven after the second factor, so codes can't be guessed endlessly
+	if services.TwoFactorEnabled(username) {
+		services.ReleaseLoginAttempt(username, c.ClientIP())
+		challengeToken, expiresAt, err := services.CreateLoginChallenge(username, passwordChangeRequired)
+		if err != nil {
+			utils.ResponseInternalServerError(c, "Failed to start two-factor login")
+			return
+		}
+		utils.ResponseOK(c, &LoginResponse{
+			Username:          username,
+			ExpiresAt:         expiresAt.Unix(),
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		})
+		return
 	}
+	services.RecordLoginSuccess(username, c.ClientIP())
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(config.GetJWTSecret())
+	response, err := issueToken(c, username, role, adminRoles, loginScope(username, role, passwordChangeRequired))
 	if err != nil {
 		utils.ResponseInternalServerError(c, "Failed to generate token")
 		return
 	}
 
-	utils.ResponseOK(c, LoginResponse{
-		Token:     tokenString,
-		Username:  credentials.Username,
-		Role:      role,
-		ExpiresAt: expirationTime.Unix(),
-	})
+	utils.ResponseOK(c, response)
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code and returns a JWT token
+func LoginTwoFactor(c *gin.Context) {
+	var req types.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	username, ok := services.LoginChallengeUsername(req.ChallengeToken)
+	if !ok {
+		utils.ResponseUnauthorized(c, "Login expired, please sign in again")
+		return
+	}
+	if rejectThrottledLogin(c, username) {
+		return
+	}
+
+	_, passwordChangeRequired, err := services.CompleteLoginChallenge(req.ChallengeToken, req.Code)
+	if err != nil {
+		services.RecordLoginFailure(username, c.ClientIP(), c.Request.UserAgent(), services.LoginFailureInvalidTwoFactorCode)
+		utils.ResponseServiceError(c, err)
+		return
+	}
+	services.RecordLoginSuccess(username, c.ClientIP())
+
+	// The account may have changed since the password was checked
+	role, adminRoles, active := accountRole(username)
+	if !active {
+		utils.ResponseUnauthorized(c, "Account is disabled or no longer exists")
+		return
+	}
+
+	response, err := issueToken(c, username, role, adminRoles, loginScope(username, role, passwordChangeRequired))
+	if err != nil {
+		utils.ResponseInternalServerError(c, "Failed to generate token")
+		return
+	}
+
+	utils.ResponseOK(c, response)
 }
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/services"
+	"golang.org/x/net/webdav"
+)
+
+// DAVPrefix is the URL prefix the WebDAV tree is served under
+const DAVPrefix = "/dav"
+
+// DAVMethods lists the HTTP methods the WebDAV handler must receive
+var DAVMethods = []string{
+	"OPTIONS", "GET", "HEAD", "POST", "PUT", "DELETE",
+	"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK",
+}
+
+// davCredentialTTL is how long a verified WebDAV password is trusted before Samba is asked again
+// WebDAV clients send credentials with every request, verifying each one would run pdbedit or smbclient constantly
+const davCredentialTTL = 1 * time.Minute
+
+type davCredentialEntry struct {
+	passwordHash [32]byte
+	expiresAt    time.Time
+}
+
+// davCredentials caches verified WebDAV passwords by username
+// Entries are dropped as soon as the account changes (see signOutUser), not only when they expire
+var davCredentials = struct {
+	sync.Mutex
+	entries map[string]davCredentialEntry
+}{entries: make(map[string]davCredentialEntry)}
+
+// forgetDAVCredentials drops the cached WebDAV password of a user
+func forgetDAVCredentials(username string) {
+	davCredentials.Lock()
+	delete(davCredentials.entries, username)
+	davCredentials.Unlock()
+}
+
+// DAVHandler serves user homes and granted shares over WebDAV with HTTP Basic authentication
+type DAVHandler struct {
+	service *services.SambaService
+
+	mu    sync.Mutex
+	locks map[string]webdav.LockSystem // Per-user lock systems, as each user sees a different tree
+}
+
+// NewDAVHandler creates a new WebDAV handler
+func NewDAVHandler(service *services.SambaService) *DAVHandler {
+	return &DAVHandler{
+		service: service,
+		locks:   make(map[string]webdav.LockSystem),
+	}
+}
+
+// authenticate verifies Basic credentials with the same Samba check and login throttling as Login
+// Refused requests are answered here
+func (h *DAVHandler) authenticate(c *gin.Context) (string, bool) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok || !loginUsernamePattern.MatchString(username) {
+		davUnauthorized(c)
+		return "", false
+	}
+
+	passwordHash := sha256.Sum256([]byte(password))
+	davCredentials.Lock()
+	entry, found := davCredentials.entries[username]
+	davCredentials.Unlock()
+	if found && time.Now().Before(entry.expiresAt) && subtle.ConstantTimeCompare(entry.passwordHash[:], passwordHash[:]) == 1 {
+		return username, true
+	}
+
+	ip := c.ClientIP()
+	if wait, allowed := services.CheckLoginAllowed(username, ip); !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatus(http.StatusTooManyRequests)
+		return "", false
+	}
+
+	valid, expired := services.CheckSambaPassword(username, password)
+	if !valid {
+		services.RecordLoginFailure(username, ip, c.Request.UserAgent(), services.LoginFailureInvalidCredentials)
+		davUnauthorized(c)
+		return "", false
+	}
+	services.RecordLoginSuccess(username, ip)
+
+	// Accounts that must change their password or use a second factor only get the web login
+	_, admin := services.GetSambaAdminRoles(username)
+	if expired || services.PasswordChangeRequired(username) || services.TwoFactorEnabled(username) ||
+		services.TwoFactorSetupRequired(username, admin) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return "", false
+	}
+
+	davCredentials.Lock()
+	davCredentials.entries[username] = davCredentialEntry{
+		passwordHash: passwordHash,
+		expiresAt:    time.Now().Add(davCredentialTTL),
+	}
+	davCredentials.Unlock()
+
+	return username, true
+}
+
+// davUnauthorized asks the WebDAV client for credentials
+func davUnauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="SambaManager WebDAV", charset="UTF-8"`)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+// lockSystem returns the lock system of a user, creating it on first use
+func (h *DAVHandler) lockSystem(username string) webdav.LockSystem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ls, found := h.locks[username]
+	if !found {
+		ls = webdav.NewMemLS()
+		h.locks[username] = ls
+	}
+	return ls
+}
+
+// ServeDAV handles a WebDAV request
+// Uses real HTTP status codes instead of the JSON envelope, as WebDAV clients depend on them
+func (h *DAVHandler) ServeDAV(c *gin.Context) {
+	username, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	davHandler := &webdav.Handler{
+		Prefix:     DAVPrefix,
+		FileSystem: services.NewDAVFileSystem(h.service, username),
+		LockSystem: h.lockSystem(username),
+	}
+	davHandler.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"net/url"
+	"path"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// FileHandler handles file browsing requests for user homes and shares
+// The same handlers serve both /user/files (own home) and /user/shared/:shareId/files
+type FileHandler struct {
+	service     *services.SambaService
+	fileService *services.FileService
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(service *services.SambaService) *FileHandler {
+	return &FileHandler{
+		service:     service,
+		fileService: services.NewFileService(),
+	}
+}
+
+// resolveRoot determines the browsable root for the request
+// Uses the share from the :shareId parameter if present, otherwise the caller's home directory
+func (h *FileHandler) resolveRoot(c *gin.Context) (*services.BrowseRoot, bool) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return nil, false
+	}
+
+	var root *services.BrowseRoot
+	var err error
+	if shareId := c.Param("shareId"); shareId != "" {
+		root, err = h.service.GetShareRoot(shareId, username)
+	} else {
+		root, err = h.service.GetHomeRoot(username)
+	}
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return nil, false
+	}
+
+	return root, true
+}
+
+// attachmentDisposition builds a Content-Disposition header value for a download
+func attachmentDisposition(filename string) string {
+	return "attachment; filename*=UTF-8''" + url.PathEscape(filename)
+}
+
+// ListSharedWithMe lists shares other users have granted to the current user
+func (h *FileHandler) ListSharedWithMe(c *gin.Context) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return
+	}
+
+	shares, err := h.service.ListSharesForUser(username)
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, shares)
+}
+
+// ListFiles lists the contents of a directory
+func (h *FileHandler) ListFiles(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	listing, err := h.fileService.ListDirectory(root, c.Query("path"))
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseOK(c, listing)
+}
+
+// DownloadFile streams a single file to the client
+func (h *FileHandler) DownloadFile(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	filePath, info, err := h.fileService.StatFile(root, c.Query("path"))
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	c.FileAttachment(filePath, info.Name())
+}
+
+// DownloadArchive streams a directory as a zip or tar.gz archive (format query, default zip)
+// The archive is generated on the fly and stops as soon as the client disconnects
+func (h *FileHandler) DownloadArchive(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	format, err := services.ParseArchiveFormat(c.Query("format"))
+	if err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	dirPath, err := h.fileService.StatDirectory(root, c.Query("path"))
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Enforce limits before streaming so the client still receives a proper error
+	limits := services.DefaultArchiveLimits()
+	if err := services.CheckArchiveLimits(c.Request.Context(), dirPath, limits); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", attachmentDisposition(format.FileName(filepath.Base(dirPath))))
+	if err := services.WriteArchive(c.Request.Context(), c.Writer, dirPath, format, limits); err != nil {
+		// Headers are already sent, the truncated archive signals the failure to the client
+		_ = c.Error(err)
+	}
+}
+
+// UploadFile stores a multipart uploaded file in the directory given by the path query
+func (h *FileHandler) UploadFile(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.ResponseBadRequest(c, "File is required")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.ResponseInternalServerError(c, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	// Only the base name of the uploaded file is used, the target directory comes from the query
+	targetPath := path.Join(c.Query("path"), path.Base(fileHeader.Filename))
+	if err := h.fileService.SaveFile(root, targetPath, file); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "File uploaded successfully")
+}
+
+// CreateDirectory creates a new directory
+func (h *FileHandler) CreateDirectory(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	var req types.CreateDirectoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.fileService.CreateDirectory(root, req.Path); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Directory created successfully")
+}
+
+// RenamePath renames or moves a file or directory
+func (h *FileHandler) RenamePath(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	var req types.RenamePathRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.fileService.RenamePath(root, req.From, req.To); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Renamed successfully")
+}
+
+// DeletePath deletes a file or directory given by the path query
+func (h *FileHandler) DeletePath(c *gin.Context) {
+	root, ok := h.resolveRoot(c)
+	if !ok {
+		return
+	}
+
+	if err := h.fileService.DeletePath(root, c.Query("path")); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Deleted successfully")
+}
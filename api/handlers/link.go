@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// LinkHandler handles public download link requests
+type LinkHandler struct {
+	service *services.LinkService
+}
+
+// NewLinkHandler creates a new link handler
+func NewLinkHandler(service *services.LinkService) *LinkHandler {
+	return &LinkHandler{
+		service: service,
+	}
+}
+
+// ListMyLinks lists download links created by the current user
+func (h *LinkHandler) ListMyLinks(c *gin.Context) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return
+	}
+
+	links, err := h.service.ListLinks(username)
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, links)
+}
+
+// CreateMyLink creates a download link for a file or folder in the current user's home
+func (h *LinkHandler) CreateMyLink(c *gin.Context) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return
+	}
+
+	var req types.CreateDownloadLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	link, err := h.service.CreateLink(username, &req)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseCreated(c, link)
+}
+
+// RevokeMyLink revokes a download link owned by the current user
+func (h *LinkHandler) RevokeMyLink(c *gin.Context) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return
+	}
+
+	if err := h.service.RevokeLink(c.Param("linkId"), username); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Link revoked successfully")
+}
+
+// ListLinks lists download links of all users (admin)
+func (h *LinkHandler) ListLinks(c *gin.Context) {
+	links, err := h.service.ListLinks("")
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, links)
+}
+
+// RevokeLink revokes any download link (admin)
+func (h *LinkHandler) RevokeLink(c *gin.Context) {
+	if err := h.service.RevokeLink(c.Param("linkId"), ""); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Link revoked successfully")
+}
+
+// GetPolicy retrieves the download link policy
+func (h *LinkHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.service.GetPolicy()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, policy)
+}
+
+// UpdatePolicy updates the download link policy (admin)
+func (h *LinkHandler) UpdatePolicy(c *gin.Context) {
+	var req types.DownloadLinkPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.UpdatePolicy(&req); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Link policy updated successfully")
+}
+
+// GetPublicLink returns public information about a link (no authentication)
+func (h *LinkHandler) GetPublicLink(c *gin.Context) {
+	info, err := h.service.GetPublicLinkInfo(c.Param("token"))
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseOK(c, info)
+}
+
+// DownloadPublicLink streams the linked file, or a zip of the linked folder (no authentication)
+// The link password is only accepted as a POST form field, so it stays out of URLs and access logs
+func (h *LinkHandler) DownloadPublicLink(c *gin.Context) {
+	password := c.PostForm("password")
+
+	targetPath, info, err := h.service.OpenPublicLink(c.Param("token"), password, c.ClientIP())
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	if !info.IsDir() {
+		c.FileAttachment(targetPath, info.Name())
+		return
+	}
+
+	limits := services.DefaultArchiveLimits()
+	if err := services.CheckArchiveLimits(c.Request.Context(), targetPath, limits); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", services.ArchiveZip.ContentType())
+	c.Header("Content-Disposition", attachmentDisposition(services.ArchiveZip.FileName(info.Name())))
+	if err := services.WriteArchive(c.Request.Context(), c.Writer, targetPath, services.ArchiveZip, limits); err != nil {
+		// Headers are already sent, the truncated archive signals the failure to the client
+		_ = c.Error(err)
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// LoginProtectionHandler handles requests about failed logins and lockouts
+type LoginProtectionHandler struct{}
+
+// NewLoginProtectionHandler creates a new login protection handler
+func NewLoginProtectionHandler() *LoginProtectionHandler {
+	return &LoginProtectionHandler{}
+}
+
+// ListThrottles lists usernames and IP addresses with recent failed logins, including lockouts
+func (h *LoginProtectionHandler) ListThrottles(c *gin.Context) {
+	throttles, err := services.ListLoginThrottles()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, throttles)
+}
+
+// Unlock clears the failed logins and lockout of a username or IP address
+func (h *LoginProtectionHandler) Unlock(c *gin.Context) {
+	var req types.UnlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := services.UnlockLogin(req.Kind, req.Value); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Login unlocked successfully")
+}
+
+// ListFailures returns the audit log of failed logins
+func (h *LoginProtectionHandler) ListFailures(c *gin.Context) {
+	var query types.LoginFailureQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	failures, err := services.ListLoginFailures(&query)
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, failures)
+}
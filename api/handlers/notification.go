@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// NotificationHandler handles admin notification requests
+type NotificationHandler struct {
+	service *services.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(service *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		service: service,
+	}
+}
+
+// ListNotifications lists admin notifications, newest first
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	notifications, err := h.service.ListNotifications()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, notifications)
+}
+
+// MarkRead marks a notification as read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	if err := h.service.MarkRead(c.Param("notificationId")); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Notification marked as read")
+}
+
+// MarkAllRead marks all notifications as read
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	if err := h.service.MarkRead(""); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "All notifications marked as read")
+}
+
+// DeleteNotification deletes a notification
+func (h *NotificationHandler) DeleteNotification(c *gin.Context) {
+	if err := h.service.DeleteNotification(c.Param("notificationId")); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Notification deleted successfully")
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/oidc"
+)
+
+// OIDCHandler handles single sign-on through an OpenID Connect identity provider
+type OIDCHandler struct {
+	service *services.OIDCService
+}
+
+// NewOIDCHandler creates a new OIDC handler
+func NewOIDCHandler(service *services.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		service: service,
+	}
+}
+
+// GetConfig tells the login page whether to offer single sign-on
+func (h *OIDCHandler) GetConfig(c *gin.Context) {
+	utils.ResponseOK(c, &types.OIDCConfigResponse{Enabled: h.service.Enabled()})
+}
+
+// setStateCookie binds the authorization request to the browser that started it
+func setStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.HasPrefix(config.AppConfig.OIDC.RedirectURL, "https://")
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcStateCookiePath,
+		MaxAge:   maxAge,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode, // Sent along with the provider's top-level redirect back
+	})
+}
+
+// Login sends the browser to the identity provider
+func (h *OIDCHandler) Login(c *gin.Context) {
+	state, authURL, err := h.service.BeginLogin()
+	if err != nil {
+		log.Printf("Failed to start single sign-on: %v", err)
+		h.redirectToFrontend(c, "error", ssoErrorMessage(err))
+		return
+	}
+
+	setStateCookie(c, state, 600)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes the sign-in at the identity provider and returns the browser to the web UI
+// with a one-time code, which the login page exchanges for tokens via Exchange
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	setStateCookie(c, "", -1)
+
+	if providerError := c.Query("error"); providerError != "" {
+		log.Printf("Identity provider returned an error: %s %s", providerError, c.Query("error_description"))
+		h.redirectToFrontend(c, "error", "Sign-in was cancelled or denied by the identity provider")
+		return
+	}
+
+	state := c.Query("state")
+	if state == "" || cookieState != state {
+		h.redirectToFrontend(c, "error", "Sign-in expired, please try again")
+		return
+	}
+
+	username, rolesChanged, err := h.service.CompleteLogin(state, c.Query("code"))
+	if err != nil {
+		log.Printf("Single sign-on failed: %v", err)
+		h.redirectToFrontend(c, "error", ssoErrorMessage(err))
+		return
+	}
+	if rolesChanged {
+		// Existing tokens carry the old roles
+		if _, err := services.RevokeUserSessions(username, ""); err != nil {
+			log.Printf("Failed to revoke sessions of %s: %v", username, err)
+		}
+	}
+
+	code, err := h.service.IssueLoginCode(username)
+	if err != nil {
+		h.redirectToFrontend(c, "error", "Single sign-on failed")
+		return
+	}
+	h.redirectToFrontend(c, "code", code)
+}
+
+// Exchange redeems the one-time code from Callback and finishes the login like a password login
+func (h *OIDCHandler) Exchange(c *gin.Context) {
+	var req types.OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	username, ok := h.service.RedeemLoginCode(req.Code)
+	if !ok {
+		utils.ResponseUnauthorized(c, "Sign-in expired, please try again")
+		return
+	}
+
+	// Reserve an attempt like a password login, finishLogin ends it
+	if rejectThrottledLogin(c, username) {
+		return
+	}
+
+	role, adminRoles, active := accountRole(username)
+	if !active {
+		services.RecordLoginFailure(username, c.ClientIP(), c.Request.UserAgent(), services.LoginFailureAccountDisabled)
+		utils.ResponseUnauthorized(c, "Account is disabled or no longer exists")
+		return
+	}
+
+	// The Samba password isn't used, so its expiry doesn't apply
+	finishLogin(c, username, role, adminRoles, false)
+}
+
+// redirectToFrontend returns the browser to the single sign-on page of the web UI
+func (h *OIDCHandler) redirectToFrontend(c *gin.Context, key string, value string) {
+	c.Redirect(http.StatusFound, config.AppConfig.OIDC.FrontendPath+"?"+url.Values{key: {value}}.Encode())
+}
+
+// ssoErrorMessage returns the message shown for a failed sign-on, hiding internal errors
+func ssoErrorMessage(err error) string {
+	var notFoundErr *utils.NotFoundError
+	var forbiddenErr *utils.ForbiddenError
+	var unauthorizedErr *utils.UnauthorizedError
+	if errors.As(err, &notFoundErr) || errors.As(err, &forbiddenErr) || errors.As(err, &unauthorizedErr) {
+		return err.Error()
+	}
+	return "Single sign-on failed"
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// OrphanRetentionHandler handles orphan retention policy requests
+type OrphanRetentionHandler struct {
+	service *services.OrphanRetentionService
+}
+
+// NewOrphanRetentionHandler creates a new orphan retention handler
+func NewOrphanRetentionHandler(service *services.OrphanRetentionService) *OrphanRetentionHandler {
+	return &OrphanRetentionHandler{
+		service: service,
+	}
+}
+
+// GetPolicy retrieves the orphan retention policy
+func (h *OrphanRetentionHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.service.GetPolicy()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, policy)
+}
+
+// UpdatePolicy updates the orphan retention policy
+func (h *OrphanRetentionHandler) UpdatePolicy(c *gin.Context) {
+	var req types.OrphanRetentionPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.UpdatePolicy(&req); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Retention policy updated successfully")
+}
+
+// Preview shows what the next scheduled run will do
+func (h *OrphanRetentionHandler) Preview(c *gin.Context) {
+	preview, err := h.service.Preview()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, preview)
+}
+
+// GetLastRun retrieves the result of the most recent run
+func (h *OrphanRetentionHandler) GetLastRun(c *gin.Context) {
+	run, err := h.service.GetLastRun()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, run)
+}
+
+// RunNow applies the retention policy immediately
+func (h *OrphanRetentionHandler) RunNow(c *gin.Context) {
+	// Not wrapped in the queue: the service submits every action to it individually
+	run, err := h.service.Run("manual")
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, run)
+}
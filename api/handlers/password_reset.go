@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/queue"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// PasswordResetHandler handles password reset tokens issued by admins and used by users
+type PasswordResetHandler struct {
+	service *services.PasswordResetService
+	queue   *queue.Queue
+}
+
+// NewPasswordResetHandler creates a new password reset handler
+func NewPasswordResetHandler(service *services.PasswordResetService, q *queue.Queue) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		service: service,
+		queue:   q,
+	}
+}
+
+// CreateReset issues a single-use reset token for a user (admin)
+// The token is only returned once and has to be handed to the user
+func (h *PasswordResetHandler) CreateReset(c *gin.Context) {
+	admin, _ := middlewares.GetUsernameFromContext(c)
+	if rejectAdminTarget(c, c.Param("username")) {
+		return
+	}
+
+	var req types.CreatePasswordResetRequest
+	// The body is optional, without one the default lifetime is used
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ResponseBadRequest(c, err.Error())
+			return
+		}
+	}
+
+	response, err := h.service.CreateReset(c.Param("username"), admin, &req)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseCreated(c, response)
+}
+
+// RevokeReset invalidates a user's unused reset token (admin)
+func (h *PasswordResetHandler) RevokeReset(c *gin.Context) {
+	if rejectAdminTarget(c, c.Param("username")) {
+		return
+	}
+
+	if err := h.service.RevokeResets(c.Param("username")); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Password reset revoked successfully")
+}
+
+// VerifyReset returns the username and password policy of a reset token (no authentication)
+func (h *PasswordResetHandler) VerifyReset(c *gin.Context) {
+	var req types.PasswordResetTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	info, err := h.service.GetResetInfo(req.Token)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseOK(c, info)
+}
+
+// CompleteReset sets a new password with a reset token (no authentication)
+func (h *PasswordResetHandler) CompleteReset(c *gin.Context) {
+	var req types.CompletePasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	var username string
+	err := h.queue.SubmitSync(func() error {
+		var err error
+		username, err = h.service.CompleteReset(req.Token, req.NewPassword)
+		return err
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	middlewares.InvalidateUserCache(username)
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Password reset successfully")
+}
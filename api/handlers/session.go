@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// SessionHandler handles logout and web session management requests
+type SessionHandler struct{}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler() *SessionHandler {
+	return &SessionHandler{}
+}
+
+// sessionResponses marks the session making the request
+func sessionResponses(c *gin.Context, sessions []types.Session) []types.SessionResponse {
+	currentID := middlewares.GetSessionIDFromContext(c)
+	responses := make([]types.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, types.SessionResponse{
+			Session: session,
+			Current: session.ID == currentID,
+		})
+	}
+	return responses
+}
+
+// signOutUser ends all web sessions and the cached WebDAV login of a user after their password, roles or account changed
+// The session making the request is kept when users change their own account
+func signOutUser(c *gin.Context, username string) {
+	exceptID := ""
+	if currentUser, _ := middlewares.GetUsernameFromContext(c); currentUser == username {
+		exceptID = middlewares.GetSessionIDFromContext(c)
+	}
+
+	if _, err := services.RevokeUserSessions(username, exceptID); err != nil {
+		log.Printf("Failed to revoke sessions of %s: %v", username, err)
+	}
+	forgetDAVCredentials(username)
+}
+
+// Logout ends the session making the request
+func (h *SessionHandler) Logout(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	if err := services.RevokeSession(middlewares.GetSessionIDFromContext(c), username); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Logged out successfully")
+}
+
+// ListMySessions lists the active web sessions of the current user
+func (h *SessionHandler) ListMySessions(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	sessions, err := services.ListSessions(username)
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, sessionResponses(c, sessions))
+}
+
+// RevokeMySession ends one of the current user's sessions
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	if err := services.RevokeSession(c.Param("sessionId"), username); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Session signed out successfully")
+}
+
+// RevokeAllMySessions signs the current user out everywhere, including this session
+func (h *SessionHandler) RevokeAllMySessions(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	revoked, err := services.RevokeUserSessions(username, "")
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, gin.H{
+		"revoked": revoked,
+	})
+}
+
+// ListSessions lists active web sessions of all users, optionally filtered by username (admin)
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	sessions, err := services.ListSessions(c.Query("username"))
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, sessionResponses(c, sessions))
+}
+
+// RevokeSession ends any user's session (admin)
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	if username, ok := services.GetSessionUsername(sessionID); ok && rejectAdminTarget(c, username) {
+		return
+	}
+
+	if err := services.RevokeSession(sessionID, ""); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Session signed out successfully")
+}
+
+// RevokeUserSessions signs a user out of all their sessions (admin)
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	var req types.RevokeSessionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+	if rejectAdminTarget(c, req.Username) {
+		return
+	}
+
+	revoked, err := services.RevokeUserSessions(req.Username, "")
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, gin.H{
+		"revoked": revoked,
+	})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// TwoFactorHandler handles two-factor authentication requests
+type TwoFactorHandler struct{}
+
+// NewTwoFactorHandler creates a new two-factor handler
+func NewTwoFactorHandler() *TwoFactorHandler {
+	return &TwoFactorHandler{}
+}
+
+// twoFactorConfirmResponse contains the recovery codes and, after a required setup, the full login
+type twoFactorConfirmResponse struct {
+	types.TwoFactorRecoveryCodesResponse
+	Login *LoginResponse `json:"login,omitempty"`
+}
+
+// isAdminRequest reports whether the current account is an admin
+func isAdminRequest(c *gin.Context) bool {
+	role, _ := middlewares.GetRoleFromContext(c)
+	return role == string(types.RoleAdmin)
+}
+
+// GetStatus returns the two-factor state of the current account
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	status, err := services.GetTwoFactorStatus(username, isAdminRequest(c))
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, status)
+}
+
+// BeginSetup generates a TOTP secret and provisioning URI for the current account
+func (h *TwoFactorHandler) BeginSetup(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	setup, err := services.BeginTwoFactorSetup(username)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseOK(c, setup)
+}
+
+// ConfirmSetup enables two-factor authentication with a first code and returns the recovery codes
+func (h *TwoFactorHandler) ConfirmSetup(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	var req types.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	codes, err := services.ConfirmTwoFactorSetup(username, req.Code)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Other sessions were signed in without the second factor
+	signOutUser(c, username)
+
+	response := twoFactorConfirmResponse{TwoFactorRecoveryCodesResponse: *codes}
+
+	// A token restricted to the required setup is swapped for a full one
+	if middlewares.IsTwoFactorSetupOnly(c) {
+		if err := services.RevokeSession(middlewares.GetSessionIDFromContext(c), username); err != nil {
+			utils.ResponseServiceError(c, err)
+			return
+		}
+
+		role, _ := middlewares.GetRoleFromContext(c)
+		login, err := issueToken(c, username, types.UserRole(role), middlewares.GetAdminRolesFromContext(c), "")
+		if err != nil {
+			utils.ResponseInternalServerError(c, "Failed to generate token")
+			return
+		}
+		response.Login = login
+	}
+
+	utils.ResponseOK(c, response)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current account
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	var req types.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	codes, err := services.RegenerateRecoveryCodes(username, req.Code)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseOK(c, codes)
+}
+
+// Disable turns off two-factor authentication of the current account, unless the policy requires it
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	username, _ := middlewares.GetUsernameFromContext(c)
+
+	var req types.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := services.DisableTwoFactor(username, req.Code, isAdminRequest(c)); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Two-factor authentication disabled successfully")
+}
+
+// GetPolicy retrieves the two-factor policy
+func (h *TwoFactorHandler) GetPolicy(c *gin.Context) {
+	policy, err := services.GetTwoFactorPolicy()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, policy)
+}
+
+// UpdatePolicy updates the two-factor policy (admin)
+func (h *TwoFactorHandler) UpdatePolicy(c *gin.Context) {
+	var req types.TwoFactorPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := services.UpdateTwoFactorPolicy(&req); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Two-factor policy updated successfully")
+}
+
+// ResetUser removes the two-factor enrollment of an account that lost its authenticator (admin)
+// Only full admins may reset the second factor of another admin
+func (h *TwoFactorHandler) ResetUser(c *gin.Context) {
+	username := c.Param("username")
+	if rejectAdminTarget(c, username) {
+		return
+	}
+
+	if err := services.ResetTwoFactor(username); err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Existing sessions end, the next login sets up the second factor again if required
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Two-factor authentication reset successfully")
+}
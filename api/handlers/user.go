@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/api/middlewares"
 	"github.com/itsHenry35/SambaManager/queue"
 	"github.com/itsHenry35/SambaManager/services"
 	"github.com/itsHenry35/SambaManager/types"
@@ -12,15 +13,17 @@ import (
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
-	service *services.SambaService
-	queue   *queue.Queue
+	service     *services.SambaService
+	linkService *services.LinkService
+	queue       *queue.Queue
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(service *services.SambaService, q *queue.Queue) *UserHandler {
+func NewUserHandler(service *services.SambaService, linkService *services.LinkService, q *queue.Queue) *UserHandler {
 	return &UserHandler{
-		service: service,
-		queue:   q,
+		service:     service,
+		linkService: linkService,
+		queue:       q,
 	}
 }
 
@@ -40,11 +43,18 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 	// Submit to queue for processing
 	err := h.queue.SubmitSync(func() error {
-		return h.service.CreateUser(user)
+		if err := h.service.CreateUser(user); err != nil {
+			return err
+		}
+		// The initial password is only known to the admin, so it has to be replaced on first login
+		if req.MustChangePassword == nil || *req.MustChangePassword {
+			return h.service.RequirePasswordChange(user.Username)
+		}
+		return nil
 	})
 
 	if err != nil {
-		utils.ResponseInternalServerError(c, err.Error())
+		utils.ResponseServiceError(c, err)
 		return
 	}
 
@@ -60,6 +70,9 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		utils.ResponseBadRequest(c, "Username is required")
 		return
 	}
+	if rejectAdminTarget(c, username) {
+		return
+	}
 
 	var req types.DeleteUserRequest
 	// If no body provided, default to deleting home directory (backward compatible)
@@ -69,7 +82,11 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	// Submit to queue for processing
 	err := h.queue.SubmitSync(func() error {
-		return h.service.DeleteUser(username, req.DeleteHomeDir)
+		if err := h.service.DeleteUser(username, req.DeleteHomeDir); err != nil {
+			return err
+		}
+		// Public links of a deleted user must not come back if the name is reused
+		return h.linkService.RevokeUserLinks(username)
 	})
 
 	if err != nil {
@@ -77,17 +94,21 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// Sessions and admin rights of the deleted user end immediately
+	middlewares.InvalidateUserCache(username)
+	signOutUser(c, username)
+
 	utils.ResponseSuccessWithCustomMessage(c, "User deleted successfully")
 }
 
-// ListUsers retrieves system users with pagination and search
+// ListUsers retrieves system users with pagination, search, status filter and sorting
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	var query types.PaginationQuery
+	var query types.UserListQuery
 	if err := c.ShouldBindQuery(&query); err != nil {
 		utils.ResponseBadRequest(c, err.Error())
 		return
 	}
-	query = query.GetDefaults()
+	query.PaginationQuery = query.GetDefaults()
 
 	users, err := h.service.ListUsers()
 	if err != nil {
@@ -95,17 +116,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		return
 	}
 
-	// Filter by search query
-	filteredUsers := users
-	if query.Search != "" {
-		filteredUsers = []types.UserResponse{}
-		searchLower := strings.ToLower(query.Search)
-		for _, user := range users {
-			if strings.Contains(strings.ToLower(user.Username), searchLower) {
-				filteredUsers = append(filteredUsers, user)
-			}
-		}
-	}
+	filteredUsers := services.FilterAndSortUsers(users, &query)
 
 	// Calculate pagination
 	total := len(filteredUsers)
@@ -124,6 +135,43 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	utils.ResponsePaginated(c, paginatedUsers, total, query.Page, query.PageSize)
 }
 
+// GetUser retrieves all details of a single user
+func (h *UserHandler) GetUser(c *gin.Context) {
+	user, err := h.service.GetUser(c.Param("username"))
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseOK(c, user)
+}
+
+// UpdateUserProfile replaces a user's display name, email, department and notes
+func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
+	username := c.Param("username")
+	if rejectAdminTarget(c, username) {
+		return
+	}
+
+	var req types.UserProfile
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		return h.service.UpdateUserProfile(username, &req)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Profile updated successfully")
+}
+
 // ChangePassword changes a user's password
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	username := c.Param("username")
@@ -137,6 +185,9 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		utils.ResponseBadRequest(c, "Invalid username length")
 		return
 	}
+	if rejectAdminTarget(c, username) {
+		return
+	}
 
 	var req types.ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -146,17 +197,159 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 
 	// Submit to queue for processing
 	err := h.queue.SubmitSync(func() error {
-		return h.service.ChangePassword(username, req.Password)
+		if err := h.service.ChangePassword(username, req.Password); err != nil {
+			return err
+		}
+		if req.MustChangePassword {
+			return h.service.RequirePasswordChange(username)
+		}
+		return nil
 	})
 
 	if err != nil {
-		utils.ResponseInternalServerError(c, err.Error())
+		utils.ResponseServiceError(c, err)
 		return
 	}
 
+	// Sessions signed in with the old password end
+	signOutUser(c, username)
+
 	utils.ResponseSuccessWithCustomMessage(c, "Password changed successfully")
 }
 
+// SetPasswordExpiry sets whether a user must change their password and how long passwords stay valid
+func (h *UserHandler) SetPasswordExpiry(c *gin.Context) {
+	username := c.Param("username")
+	if rejectAdminTarget(c, username) {
+		return
+	}
+
+	var req types.PasswordExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		return h.service.SetPasswordExpiry(username, &req)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// WebDAV is refused once a password change is required
+	forgetDAVCredentials(username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "Password expiry updated successfully")
+}
+
+// GetPasswordPolicy retrieves the password policy (also used by forms to show requirements)
+func (h *UserHandler) GetPasswordPolicy(c *gin.Context) {
+	policy, err := h.service.GetPasswordPolicy()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, policy)
+}
+
+// UpdatePasswordPolicy updates the password policy (admin)
+func (h *UserHandler) UpdatePasswordPolicy(c *gin.Context) {
+	var req types.PasswordPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.UpdatePasswordPolicy(&req); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Password policy updated successfully")
+}
+
+// RenameUser renames a user, moving their home directory, shares and links
+func (h *UserHandler) RenameUser(c *gin.Context) {
+	username := c.Param("username")
+	if rejectAdminTarget(c, username) {
+		return
+	}
+
+	var req types.RenameUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		if err := h.service.RenameUser(username, req.NewUsername); err != nil {
+			return err
+		}
+		return h.linkService.RenameUserLinks(username, req.NewUsername)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Sessions of the old name must not outlive the rename
+	middlewares.InvalidateUserCache(username)
+	middlewares.InvalidateUserCache(req.NewUsername)
+	signOutUser(c, username)
+
+	utils.ResponseSuccessWithCustomMessage(c, "User renamed successfully")
+}
+
+// DisableUser disables a user's account without deleting it
+func (h *UserHandler) DisableUser(c *gin.Context) {
+	h.setUserEnabled(c, false)
+}
+
+// EnableUser re-enables a disabled or locked out account
+func (h *UserHandler) EnableUser(c *gin.Context) {
+	h.setUserEnabled(c, true)
+}
+
+// setUserEnabled disables or enables the account given by the username parameter
+func (h *UserHandler) setUserEnabled(c *gin.Context, enabled bool) {
+	username := c.Param("username")
+	if rejectAdminTarget(c, username) {
+		return
+	}
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		return h.service.SetUserEnabled(username, enabled)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Apply the new status to existing web sessions immediately
+	middlewares.InvalidateUserCache(username)
+	if !enabled {
+		signOutUser(c, username)
+	} else {
+		// Re-enabling an account also lifts a web login lockout
+		_ = services.UnlockLogin(types.LoginThrottleUsername, username)
+	}
+
+	if enabled {
+		utils.ResponseSuccessWithCustomMessage(c, "User enabled successfully")
+	} else {
+		utils.ResponseSuccessWithCustomMessage(c, "User disabled successfully")
+	}
+}
+
 // ListOrphanedDirectories lists home directories without corresponding users
 func (h *UserHandler) ListOrphanedDirectories(c *gin.Context) {
 	orphanedDirs, err := h.service.ListOrphanedDirectories()
@@ -168,6 +361,21 @@ func (h *UserHandler) ListOrphanedDirectories(c *gin.Context) {
 	utils.ResponseOK(c, orphanedDirs)
 }
 
+// GetOrphanSizeStatus returns the progress of the background orphan size calculation
+func (h *UserHandler) GetOrphanSizeStatus(c *gin.Context) {
+	utils.ResponseOK(c, h.service.GetOrphanSizeStatus())
+}
+
+// RecalculateOrphanSizes queues all orphaned directories for a fresh size calculation
+func (h *UserHandler) RecalculateOrphanSizes(c *gin.Context) {
+	if err := h.service.RecalculateOrphanSizes(); err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, h.service.GetOrphanSizeStatus())
+}
+
 // DeleteOrphanedDirectory deletes an orphaned home directory
 func (h *UserHandler) DeleteOrphanedDirectory(c *gin.Context) {
 	dirName := c.Param("dirName")
@@ -189,7 +397,124 @@ func (h *UserHandler) DeleteOrphanedDirectory(c *gin.Context) {
 	utils.ResponseSuccessWithCustomMessage(c, "Orphaned directory deleted successfully")
 }
 
-// SearchUsers searches for users by username (for autocomplete)
+// AdoptOrphanedDirectory recreates the user of an orphaned directory or merges it into another user's home
+func (h *UserHandler) AdoptOrphanedDirectory(c *gin.Context) {
+	dirName := c.Param("dirName")
+	if dirName == "" {
+		utils.ResponseBadRequest(c, "Directory name is required")
+		return
+	}
+
+	var req types.AdoptOrphanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	var result *types.AdoptOrphanResult
+	err := h.queue.SubmitSync(func() error {
+		adopted, err := h.service.AdoptOrphanedDirectory(dirName, &req)
+		result = adopted
+		return err
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithMessageAndData(c, "Orphaned directory adopted successfully", result)
+}
+
+// ArchiveOrphanedDirectory compresses an orphaned home directory into the archive directory
+func (h *UserHandler) ArchiveOrphanedDirectory(c *gin.Context) {
+	dirName := c.Param("dirName")
+	if dirName == "" {
+		utils.ResponseBadRequest(c, "Directory name is required")
+		return
+	}
+
+	// Submit to queue for processing
+	var archive *types.OrphanArchive
+	err := h.queue.SubmitSync(func() error {
+		result, err := h.service.ArchiveOrphanedDirectory(dirName)
+		archive = result
+		return err
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithMessageAndData(c, "Orphaned directory archived successfully", archive)
+}
+
+// ListOrphanArchives lists archived orphaned directories
+func (h *UserHandler) ListOrphanArchives(c *gin.Context) {
+	archives, err := h.service.ListOrphanArchives()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	utils.ResponseOK(c, archives)
+}
+
+// DownloadOrphanArchive streams an archive file to the client
+func (h *UserHandler) DownloadOrphanArchive(c *gin.Context) {
+	archive, archivePath, err := h.service.GetOrphanArchive(c.Param("archiveId"))
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	c.FileAttachment(archivePath, archive.FileName)
+}
+
+// RestoreOrphanArchive restores an archive into a new or existing user's home
+func (h *UserHandler) RestoreOrphanArchive(c *gin.Context) {
+	archiveId := c.Param("archiveId")
+
+	var req types.RestoreOrphanArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		return h.service.RestoreOrphanArchive(archiveId, &req)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Archive restored successfully")
+}
+
+// DeleteOrphanArchive permanently deletes an archive
+func (h *UserHandler) DeleteOrphanArchive(c *gin.Context) {
+	archiveId := c.Param("archiveId")
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		return h.service.DeleteOrphanArchive(archiveId)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Archive deleted successfully")
+}
+
+// SearchUsers searches for users by username, display name or email (for autocomplete)
+// Non-admins only get the names, account details are for admins
 func (h *UserHandler) SearchUsers(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
@@ -204,11 +529,23 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 		return
 	}
 
+	role, _ := middlewares.GetRoleFromContext(c)
+
 	// Filter by search query
 	searchLower := strings.ToLower(query)
 	results := []types.UserResponse{}
 	for _, user := range users {
-		if strings.Contains(strings.ToLower(user.Username), searchLower) {
+		if strings.Contains(strings.ToLower(user.Username), searchLower) ||
+			strings.Contains(strings.ToLower(user.DisplayName), searchLower) ||
+			strings.Contains(strings.ToLower(user.Email), searchLower) {
+			if role != string(types.RoleAdmin) {
+				user = types.UserResponse{
+					Username:    user.Username,
+					DisplayName: user.DisplayName,
+					Email:       user.Email,
+					HomeDir:     user.HomeDir,
+				}
+			}
 			results = append(results, user)
 			if len(results) >= limit {
 				break
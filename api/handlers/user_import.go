@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// maxImportSize is the maximum size of an uploaded import file
+const maxImportSize = 10 << 20
+
+var errImportTooLarge = errors.New("import file is too large")
+
+// readImportFile reads an import from a multipart "file" field or the raw request body
+// The format comes from the format query, the file extension or the content type
+func readImportFile(c *gin.Context) ([]byte, string, error) {
+	format := strings.ToLower(c.Query("format"))
+
+	var reader io.Reader = c.Request.Body
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		reader = file
+
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	}
+	if format == "" {
+		format = "json"
+		if strings.Contains(c.ContentType(), "csv") {
+			format = "csv"
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxImportSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImportSize {
+		return nil, "", errImportTooLarge
+	}
+
+	return data, format, nil
+}
+
+// ImportUsers creates users in bulk from a CSV or JSON file
+// Every row is validated first; if any row is invalid nothing is created
+func (h *UserHandler) ImportUsers(c *gin.Context) {
+	data, format, err := readImportFile(c)
+	if errors.Is(err, errImportTooLarge) {
+		utils.ResponseBadRequest(c, "Import file is too large")
+		return
+	}
+	if err != nil {
+		utils.ResponseBadRequest(c, "Failed to read import file")
+		return
+	}
+
+	rows, err := services.ParseUserImport(data, format)
+	if err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	results, valid, err := h.service.ValidateUserImport(rows)
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	response := types.ImportUsersResponse{
+		DryRun:  c.Query("dry_run") == "true",
+		Valid:   valid,
+		Results: results,
+	}
+	if !valid {
+		utils.ResponseErrorWithData(c, http.StatusBadRequest, "Validation failed, no users were created", response)
+		return
+	}
+	if response.DryRun {
+		utils.ResponseOK(c, response)
+		return
+	}
+
+	// Each user is a separate task so other requests are not blocked for the whole import
+	for i := range rows {
+		result := &response.Results[i]
+		err := h.queue.SubmitSync(func() error {
+			return h.service.ImportUser(&rows[i], result)
+		})
+		if err != nil {
+			result.Status = types.ImportStatusFailed
+			result.Error = err.Error()
+			response.Failed++
+			continue
+		}
+		result.Status = types.ImportStatusCreated
+		response.Created++
+	}
+
+	utils.ResponseOK(c, response)
+}
+
+// ExportUsers downloads all users with their metadata and shares as CSV or JSON
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		utils.ResponseBadRequest(c, "Unsupported export format: "+format)
+		return
+	}
+
+	users, err := h.service.ExportUsers()
+	if err != nil {
+		utils.ResponseInternalServerError(c, err.Error())
+		return
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(users, "", "  ")
+		if err != nil {
+			utils.ResponseInternalServerError(c, err.Error())
+			return
+		}
+		c.Header("Content-Disposition", attachmentDisposition("users.json"))
+		c.Data(http.StatusOK, "application/json", data)
+		return
+	}
+
+	c.Header("Content-Disposition", attachmentDisposition("users.csv"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := services.WriteUserExportCSV(c.Writer, users); err != nil {
+		_ = c.Error(err)
+	}
+}
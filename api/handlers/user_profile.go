@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"os/exec"
 	"regexp"
 
 	"github.com/gin-gonic/gin"
@@ -49,27 +48,95 @@ func (h *UserProfileHandler) ChangeOwnPassword(c *gin.Context) {
 		return
 	}
 
+	// Guessing the old password through this endpoint counts against the login throttle
+	if rejectThrottledLogin(c, username) {
+		return
+	}
+
 	// Verify old password and change in one queue operation
+	verified := false
 	err := h.queue.SubmitSync(func() error {
-		// Verify old password using smbclient
+		// Verify old password, an expired one is still accepted here
 		// SECURITY: username is already validated with regex, password is passed safely
-		cmd := exec.Command("smbclient", "-L", "localhost", "-U", username+"%"+req.OldPassword, "-N")
-		if err := cmd.Run(); err != nil {
+		if valid, _ := services.CheckSambaPassword(username, req.OldPassword); !valid {
 			return utils.NewUnauthorizedError("Invalid old password")
 		}
+		verified = true
 
 		// Change password
 		return h.service.ChangePassword(username, req.NewPassword)
 	})
+	finishPasswordCheck(c, username, verified)
 
 	if err != nil {
-		if unauthorizedErr, ok := err.(*utils.UnauthorizedError); ok {
-			utils.ResponseUnauthorized(c, unauthorizedErr.Error())
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	// Other sessions end with the old password
+	signOutUser(c, username)
+
+	// A token restricted to the password change is swapped for a full one (or one for a required two-factor setup)
+	if middlewares.IsPasswordChangeOnly(c) {
+		if err := services.RevokeSession(middlewares.GetSessionIDFromContext(c), username); err != nil {
+			utils.ResponseServiceError(c, err)
 			return
 		}
-		utils.ResponseInternalServerError(c, err.Error())
+
+		role, _ := middlewares.GetRoleFromContext(c)
+		response, err := issueToken(c, username, types.UserRole(role), middlewares.GetAdminRolesFromContext(c), loginScope(username, types.UserRole(role), false))
+		if err != nil {
+			utils.ResponseInternalServerError(c, "Failed to generate token")
+			return
+		}
+		utils.ResponseOK(c, response)
 		return
 	}
 
 	utils.ResponseSuccessWithCustomMessage(c, "Password changed successfully")
 }
+
+// GetOwnProfile returns the current user's profile (without admin notes)
+func (h *UserProfileHandler) GetOwnProfile(c *gin.Context) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return
+	}
+
+	profile, err := h.service.GetUserProfile(username)
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+	profile.Notes = ""
+
+	utils.ResponseOK(c, profile)
+}
+
+// UpdateOwnProfile lets a user change their own display name and email
+func (h *UserProfileHandler) UpdateOwnProfile(c *gin.Context) {
+	username, exists := middlewares.GetUsernameFromContext(c)
+	if !exists {
+		utils.ResponseUnauthorized(c, "User not found in context")
+		return
+	}
+
+	var req types.UpdateOwnProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseBadRequest(c, err.Error())
+		return
+	}
+
+	// Submit to queue for processing
+	err := h.queue.SubmitSync(func() error {
+		return h.service.UpdateOwnProfile(username, &req)
+	})
+
+	if err != nil {
+		utils.ResponseServiceError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCustomMessage(c, "Profile updated successfully")
+}
@@ -1,7 +1,7 @@
 package middlewares
 
 import (
-	"os/exec"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -9,66 +9,117 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/services"
+	"github.com/itsHenry35/SambaManager/types"
 	"github.com/itsHenry35/SambaManager/utils"
 )
 
 // Claims represents JWT token claims
 type Claims struct {
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Roles    []string `json:"roles,omitempty"` // Admin roles, e.g. "user-manager" (admins only)
+	Scope    string   `json:"scope,omitempty"` // Empty for full access
 	jwt.RegisteredClaims
 }
 
-// userExistenceCache caches the existence status of users
-type userExistenceCache struct {
+// Token scopes, tokens without a scope have full access
+const (
+	// ScopePasswordChange restricts a token to changing the user's expired password
+	ScopePasswordChange = "password_change"
+	// ScopeTwoFactorSetup restricts a token to setting up two-factor authentication required by the policy
+	ScopeTwoFactorSetup = "two_factor_setup"
+)
+
+// tokenScope lists the only routes a restricted token may use
+type tokenScope struct {
+	routes  map[string]bool
+	message string
+}
+
+var tokenScopes = map[string]tokenScope{
+	ScopePasswordChange: {
+		routes: map[string]bool{
+			"PUT /api/user/password":        true,
+			"GET /api/user/password-policy": true,
+			"POST /api/logout":              true,
+		},
+		message: "Password change required",
+	},
+	ScopeTwoFactorSetup: {
+		routes: map[string]bool{
+			"GET /api/user/two-factor":          true,
+			"POST /api/user/two-factor/setup":   true,
+			"POST /api/user/two-factor/confirm": true,
+			"POST /api/logout":                  true,
+		},
+		message: "Two-factor authentication setup required",
+	},
+}
+
+// userStatusCache caches the existence, enabled status and admin roles of users
+type userStatusCache struct {
 	mu    sync.RWMutex
 	cache map[string]cacheEntry
 }
 
+// userStatus is the current state of an account, checked on every request
+type userStatus struct {
+	exists     bool
+	active     bool
+	admin      bool     // Web administrator or Samba user promoted to admin
+	adminRoles []string // Admin roles, empty for regular users
+}
+
 type cacheEntry struct {
-	exists    bool
+	status    userStatus
 	expiresAt time.Time
 }
 
-var userCache = &userExistenceCache{
+var userCache = &userStatusCache{
 	cache: make(map[string]cacheEntry),
 }
 
 const cacheTTL = 1 * time.Minute
 
-// checkUserExists verifies if a user exists in Samba, with caching
-func (uc *userExistenceCache) checkUserExists(username string) bool {
+// checkUserStatus looks up whether a user exists, is not disabled and holds admin roles, with caching
+// Changes made through the API invalidate the cache, so they apply immediately
+func (uc *userStatusCache) checkUserStatus(username string) userStatus {
 	// Check cache first
 	uc.mu.RLock()
 	if entry, found := uc.cache[username]; found && time.Now().Before(entry.expiresAt) {
 		uc.mu.RUnlock()
-		return entry.exists
+		return entry.status
 	}
 	uc.mu.RUnlock()
 
-	// Cache miss or expired, check actual user existence
-	exists := false
-
-	// Check if it's the admin user
-	if username == config.AppConfig.Admin.Username {
-		exists = true
+	// Cache miss or expired, check actual user status
+	var status userStatus
+	if roles, ok := services.GetAdminRoles(username); ok {
+		// Web administrators are not Samba users
+		status = userStatus{exists: true, active: true, admin: true, adminRoles: roles}
 	} else {
-		// Check regular user via pdbedit
-		cmd := exec.Command("pdbedit", "-L", "-u", username)
-		if _, err := cmd.CombinedOutput(); err == nil {
-			exists = true
-		}
+		// Check regular user via pdbedit, who may have been promoted to admin
+		status.exists, status.active = services.GetUserStatus(username)
+		status.adminRoles, status.admin = services.GetSambaAdminRoles(username)
 	}
 
 	// Update cache
 	uc.mu.Lock()
 	uc.cache[username] = cacheEntry{
-		exists:    exists,
+		status:    status,
 		expiresAt: time.Now().Add(cacheTTL),
 	}
 	uc.mu.Unlock()
 
-	return exists
+	return status
+}
+
+// InvalidateUserCache drops the cached status of a user so the next request re-checks it
+func InvalidateUserCache(username string) {
+	userCache.mu.Lock()
+	delete(userCache.cache, username)
+	userCache.mu.Unlock()
 }
 
 // AuthMiddleware validates JWT token and sets username in context
@@ -97,6 +148,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		})
 
 		if err != nil || !token.Valid {
+			// Tells the web UI to renew the access token with its refresh token (RFC 6750)
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
 			utils.ResponseUnauthorized(c, "Invalid or expired token")
 			c.Abort()
 			return
@@ -104,15 +157,51 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// Extract claims
 		if claims, ok := token.Claims.(*Claims); ok {
-			// Verify user still exists
-			if !userCache.checkUserExists(claims.Username) {
+			// Verify user still exists and has not been disabled
+			status := userCache.checkUserStatus(claims.Username)
+			if !status.exists {
 				utils.ResponseUnauthorized(c, "User no longer exists")
 				c.Abort()
 				return
 			}
+			if !status.active {
+				utils.ResponseUnauthorized(c, "Account is disabled")
+				c.Abort()
+				return
+			}
+
+			// The token ID names its server-side session, which ends on logout or password and role changes
+			if !services.ValidateSession(claims.ID, claims.Username, c.ClientIP()) {
+				utils.ResponseUnauthorized(c, "Session expired or signed out")
+				c.Abort()
+				return
+			}
+
+			if claims.Scope != "" {
+				// Unknown scopes allow no routes at all
+				scope, ok := tokenScopes[claims.Scope]
+				if !ok {
+					scope.message = "Invalid token scope"
+				}
+				if !scope.routes[c.Request.Method+" "+c.FullPath()] {
+					utils.ResponseForbidden(c, scope.message)
+					c.Abort()
+					return
+				}
+			}
+
+			// The role comes from the current account state rather than the token,
+			// so granting or revoking admin rights applies to existing sessions immediately
+			role := types.RoleUser
+			if status.admin {
+				role = types.RoleAdmin
+			}
 
 			c.Set("username", claims.Username)
-			c.Set("role", claims.Role)
+			c.Set("role", string(role))
+			c.Set("roles", status.adminRoles)
+			c.Set("scope", claims.Scope)
+			c.Set("session_id", claims.ID)
 		}
 
 		c.Next()
@@ -139,6 +228,28 @@ func GetRoleFromContext(c *gin.Context) (string, bool) {
 	return roleStr, ok
 }
 
+// IsPasswordChangeOnly reports whether the request uses a token restricted to changing the password
+func IsPasswordChangeOnly(c *gin.Context) bool {
+	return c.GetString("scope") == ScopePasswordChange
+}
+
+// IsTwoFactorSetupOnly reports whether the request uses a token restricted to setting up two-factor authentication
+func IsTwoFactorSetupOnly(c *gin.Context) bool {
+	return c.GetString("scope") == ScopeTwoFactorSetup
+}
+
+// GetSessionIDFromContext retrieves the ID of the session making the request
+func GetSessionIDFromContext(c *gin.Context) string {
+	return c.GetString("session_id")
+}
+
+// GetAdminRolesFromContext retrieves the admin roles from context
+func GetAdminRolesFromContext(c *gin.Context) []string {
+	roles, _ := c.Get("roles")
+	rolesSlice, _ := roles.([]string)
+	return rolesSlice
+}
+
 // RequireAdmin middleware ensures only admin can access
 func RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -151,3 +262,28 @@ func RequireAdmin() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireAdminRole middleware allows admins holding one of the given roles
+// Full admins always pass and auditors may use read-only methods
+func RequireAdminRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminRoles := GetAdminRolesFromContext(c)
+		readOnly := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+
+		for _, role := range adminRoles {
+			if role == types.AdminRoleFull || (readOnly && role == types.AdminRoleAuditor) {
+				c.Next()
+				return
+			}
+			for _, allowed := range roles {
+				if role == allowed {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		utils.ResponseForbidden(c, "Insufficient admin permissions")
+		c.Abort()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/itsHenry35/SambaManager/api/handlers"
 	"github.com/itsHenry35/SambaManager/api/middlewares"
+	"github.com/itsHenry35/SambaManager/types"
 )
 
 // SetupRoutes configures all application routes
@@ -14,6 +15,17 @@ func SetupRoutes(
 	userShareHandler *handlers.UserShareHandler,
 	userProfileHandler *handlers.UserProfileHandler,
 	systemHandler *handlers.SystemHandler,
+	fileHandler *handlers.FileHandler,
+	linkHandler *handlers.LinkHandler,
+	davHandler *handlers.DAVHandler,
+	orphanRetentionHandler *handlers.OrphanRetentionHandler,
+	notificationHandler *handlers.NotificationHandler,
+	passwordResetHandler *handlers.PasswordResetHandler,
+	adminHandler *handlers.AdminHandler,
+	sessionHandler *handlers.SessionHandler,
+	loginProtectionHandler *handlers.LoginProtectionHandler,
+	twoFactorHandler *handlers.TwoFactorHandler,
+	oidcHandler *handlers.OIDCHandler,
 ) {
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -22,31 +34,148 @@ func SetupRoutes(
 
 	// Public routes (no authentication required)
 	router.POST("/api/login", handlers.Login)
+	router.POST("/api/login/two-factor", handlers.LoginTwoFactor)
+	router.POST("/api/token/refresh", handlers.RefreshToken)
+
+	// Single sign-on through an OpenID Connect identity provider
+	router.GET("/api/oidc/config", oidcHandler.GetConfig)
+	router.GET("/api/oidc/login", oidcHandler.Login)
+	router.GET("/api/oidc/callback", oidcHandler.Callback)
+	router.POST("/api/oidc/exchange", oidcHandler.Exchange)
+
+	// Public download links (validated by signed token and optional password)
+	router.GET("/api/public/links/:token", linkHandler.GetPublicLink)
+	router.GET("/api/public/links/:token/download", linkHandler.DownloadPublicLink)
+	router.POST("/api/public/links/:token/download", linkHandler.DownloadPublicLink)
+
+	// Self-service password reset (validated by a single-use token issued by an admin)
+	router.POST("/api/public/password-reset/verify", passwordResetHandler.VerifyReset)
+	router.POST("/api/public/password-reset", passwordResetHandler.CompleteReset)
+
+	// WebDAV access to user homes and shares (HTTP Basic auth with Samba credentials)
+	for _, method := range handlers.DAVMethods {
+		router.Handle(method, handlers.DAVPrefix, davHandler.ServeDAV)
+		router.Handle(method, handlers.DAVPrefix+"/*path", davHandler.ServeDAV)
+	}
 
 	// Authenticated routes
 	api := router.Group("/api")
 	api.Use(middlewares.AuthMiddleware())
 	{
-		// Admin-only routes
+		// End the current session
+		api.POST("/logout", sessionHandler.Logout)
+
+		// Admin-only routes, each group also requires a matching admin role (auditors may read everything)
 		admin := api.Group("/admin")
 		admin.Use(middlewares.RequireAdmin())
 		{
-			// User management (admin only)
+			// User management (user managers)
 			users := admin.Group("/users")
+			users.Use(middlewares.RequireAdminRole(types.AdminRoleUserManager))
 			{
 				users.GET("", userHandler.ListUsers)
 				users.GET("/search", userHandler.SearchUsers) // For autocomplete
 				users.POST("", userHandler.CreateUser)
+				users.POST("/import", userHandler.ImportUsers)
+				users.GET("/export", userHandler.ExportUsers)
+				users.GET("/:username", userHandler.GetUser)
 				users.DELETE("/:username", userHandler.DeleteUser)
 				users.PUT("/:username/password", userHandler.ChangePassword)
+				users.PUT("/:username/password-expiry", userHandler.SetPasswordExpiry)
+				users.POST("/:username/password-reset", passwordResetHandler.CreateReset)
+				users.DELETE("/:username/password-reset", passwordResetHandler.RevokeReset)
+				users.PUT("/:username/profile", userHandler.UpdateUserProfile)
+				users.POST("/:username/rename", userHandler.RenameUser)
+				users.POST("/:username/disable", userHandler.DisableUser)
+				users.POST("/:username/enable", userHandler.EnableUser)
 
 				// Orphaned directories management
 				users.GET("/orphaned", userHandler.ListOrphanedDirectories)
+				users.GET("/orphaned/size-status", userHandler.GetOrphanSizeStatus)
+				users.POST("/orphaned/recalculate", userHandler.RecalculateOrphanSizes)
 				users.DELETE("/orphaned/:dirName", userHandler.DeleteOrphanedDirectory)
+				users.POST("/orphaned/:dirName/archive", userHandler.ArchiveOrphanedDirectory)
+				users.POST("/orphaned/:dirName/adopt", userHandler.AdoptOrphanedDirectory)
+			}
+
+			// Web administrator accounts (full admins, every admin may change their own password)
+			admins := admin.Group("/admins")
+			{
+				admins.GET("", middlewares.RequireAdminRole(), adminHandler.ListAdmins)
+				admins.POST("", middlewares.RequireAdminRole(), adminHandler.CreateAdmin)
+				admins.DELETE("/:username", middlewares.RequireAdminRole(), adminHandler.DeleteAdmin)
+				admins.PUT("/:username/password", adminHandler.ChangeAdminPassword)
+				admins.PUT("/:username/roles", middlewares.RequireAdminRole(), adminHandler.UpdateAdminRoles)
+			}
+
+			// Samba users promoted to admin (full admins)
+			sambaAdmins := admin.Group("/samba-admins")
+			sambaAdmins.Use(middlewares.RequireAdminRole())
+			{
+				sambaAdmins.PUT("/:username", adminHandler.GrantSambaAdmin)
+				sambaAdmins.DELETE("/:username", adminHandler.RevokeSambaAdmin)
+			}
+
+			// Web sessions of all users (user managers)
+			sessions := admin.Group("/sessions")
+			sessions.Use(middlewares.RequireAdminRole(types.AdminRoleUserManager))
+			{
+				sessions.GET("", sessionHandler.ListSessions)
+				sessions.POST("/revoke", sessionHandler.RevokeUserSessions)
+				sessions.DELETE("/:sessionId", sessionHandler.RevokeSession)
 			}
 
-			// Share management (admin only - full control)
+			// Failed logins and lockouts (user managers)
+			loginProtection := admin.Group("/login-protection")
+			loginProtection.Use(middlewares.RequireAdminRole(types.AdminRoleUserManager))
+			{
+				loginProtection.GET("/throttles", loginProtectionHandler.ListThrottles)
+				loginProtection.POST("/unlock", loginProtectionHandler.Unlock)
+				loginProtection.GET("/failures", loginProtectionHandler.ListFailures)
+			}
+
+			// Two-factor authentication (policy editable by full admins, resets by user managers)
+			admin.GET("/two-factor-policy", middlewares.RequireAdminRole(types.AdminRoleUserManager), twoFactorHandler.GetPolicy)
+			admin.PUT("/two-factor-policy", middlewares.RequireAdminRole(), twoFactorHandler.UpdatePolicy)
+			admin.DELETE("/two-factor/:username", middlewares.RequireAdminRole(types.AdminRoleUserManager), twoFactorHandler.ResetUser)
+
+			// Password policy (readable by user managers, editable by full admins)
+			admin.GET("/password-policy", middlewares.RequireAdminRole(types.AdminRoleUserManager), userHandler.GetPasswordPolicy)
+			admin.PUT("/password-policy", middlewares.RequireAdminRole(), userHandler.UpdatePasswordPolicy)
+
+			// Archived orphaned directories (user managers)
+			orphanArchives := admin.Group("/orphan-archives")
+			orphanArchives.Use(middlewares.RequireAdminRole(types.AdminRoleUserManager))
+			{
+				orphanArchives.GET("", userHandler.ListOrphanArchives)
+				orphanArchives.GET("/:archiveId/download", userHandler.DownloadOrphanArchive)
+				orphanArchives.POST("/:archiveId/restore", userHandler.RestoreOrphanArchive)
+				orphanArchives.DELETE("/:archiveId", userHandler.DeleteOrphanArchive)
+			}
+
+			// Automatic orphan retention (user managers)
+			orphanRetention := admin.Group("/orphan-retention")
+			orphanRetention.Use(middlewares.RequireAdminRole(types.AdminRoleUserManager))
+			{
+				orphanRetention.GET("/policy", orphanRetentionHandler.GetPolicy)
+				orphanRetention.PUT("/policy", orphanRetentionHandler.UpdatePolicy)
+				orphanRetention.GET("/preview", orphanRetentionHandler.Preview)
+				orphanRetention.GET("/last-run", orphanRetentionHandler.GetLastRun)
+				orphanRetention.POST("/run", orphanRetentionHandler.RunNow)
+			}
+
+			// Admin notifications (all admins)
+			notifications := admin.Group("/notifications")
+			{
+				notifications.GET("", notificationHandler.ListNotifications)
+				notifications.PUT("/read", notificationHandler.MarkAllRead)
+				notifications.PUT("/:notificationId/read", notificationHandler.MarkRead)
+				notifications.DELETE("/:notificationId", notificationHandler.DeleteNotification)
+			}
+
+			// Share management (share managers - full control)
 			shares := admin.Group("/shares")
+			shares.Use(middlewares.RequireAdminRole(types.AdminRoleShareManager))
 			{
 				shares.GET("", shareHandler.ListShares)
 				shares.POST("", shareHandler.CreateShare)
@@ -54,8 +183,9 @@ func SetupRoutes(
 				shares.DELETE("/:shareId", shareHandler.DeleteShare)
 			}
 
-			// System management (admin only)
+			// System management (full admins)
 			system := admin.Group("/system")
+			system.Use(middlewares.RequireAdminRole())
 			{
 				system.GET("/check", systemHandler.CheckEnvironment)
 				system.GET("/config", systemHandler.GetSambaConfig)
@@ -64,6 +194,16 @@ func SetupRoutes(
 				system.PUT("/config/file", systemHandler.UpdateSambaConfigFile)
 				system.GET("/status", systemHandler.GetSambaStatus)
 			}
+
+			// Public download link management (share managers)
+			links := admin.Group("/links")
+			links.Use(middlewares.RequireAdminRole(types.AdminRoleShareManager))
+			{
+				links.GET("", linkHandler.ListLinks)
+				links.DELETE("/:linkId", linkHandler.RevokeLink)
+				links.GET("/policy", linkHandler.GetPolicy)
+				links.PUT("/policy", linkHandler.UpdatePolicy)
+			}
 		}
 
 		// User routes (accessible by any authenticated user)
@@ -78,9 +218,58 @@ func SetupRoutes(
 
 			// User profile management
 			user.PUT("/password", userProfileHandler.ChangeOwnPassword)
+			user.GET("/password-policy", userHandler.GetPasswordPolicy)
+			user.GET("/profile", userProfileHandler.GetOwnProfile)
+			user.PUT("/profile", userProfileHandler.UpdateOwnProfile)
+
+			// Two-factor authentication of the user's own account
+			twoFactor := user.Group("/two-factor")
+			{
+				twoFactor.GET("", twoFactorHandler.GetStatus)
+				twoFactor.POST("/setup", twoFactorHandler.BeginSetup)
+				twoFactor.POST("/confirm", twoFactorHandler.ConfirmSetup)
+				twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+				twoFactor.DELETE("", twoFactorHandler.Disable)
+			}
+
+			// The user's own web sessions
+			user.GET("/sessions", sessionHandler.ListMySessions)
+			user.DELETE("/sessions", sessionHandler.RevokeAllMySessions)
+			user.DELETE("/sessions/:sessionId", sessionHandler.RevokeMySession)
 
 			// User search (for sharing purposes)
 			user.GET("/users/search", userHandler.SearchUsers)
+
+			// File browsing in the user's own home
+			files := user.Group("/files")
+			{
+				files.GET("", fileHandler.ListFiles)
+				files.GET("/download", fileHandler.DownloadFile)
+				files.GET("/archive", fileHandler.DownloadArchive)
+				files.POST("/upload", fileHandler.UploadFile)
+				files.POST("/mkdir", fileHandler.CreateDirectory)
+				files.PUT("/rename", fileHandler.RenamePath)
+				files.DELETE("", fileHandler.DeletePath)
+			}
+
+			// File browsing in shares granted to the user (access list and read-only enforced)
+			user.GET("/shared", fileHandler.ListSharedWithMe)
+			sharedFiles := user.Group("/shared/:shareId/files")
+			{
+				sharedFiles.GET("", fileHandler.ListFiles)
+				sharedFiles.GET("/download", fileHandler.DownloadFile)
+				sharedFiles.GET("/archive", fileHandler.DownloadArchive)
+				sharedFiles.POST("/upload", fileHandler.UploadFile)
+				sharedFiles.POST("/mkdir", fileHandler.CreateDirectory)
+				sharedFiles.PUT("/rename", fileHandler.RenamePath)
+				sharedFiles.DELETE("", fileHandler.DeletePath)
+			}
+
+			// Public download links for files and folders in the user's home
+			user.GET("/links", linkHandler.ListMyLinks)
+			user.POST("/links", linkHandler.CreateMyLink)
+			user.DELETE("/links/:linkId", linkHandler.RevokeMyLink)
+			user.GET("/links/policy", linkHandler.GetPolicy)
 		}
 	}
 }
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,19 +15,56 @@ import (
 type Config struct {
 	Admin struct {
 		Username string `yaml:"username"`
-		Password string `yaml:"password"`
+		Password string `yaml:"password"` // Plaintext, moved to the hashed admin accounts on start and cleared
 	} `yaml:"admin"`
 	HomeDir string `yaml:"home_dir"`
+	DataDir string `yaml:"data_dir"` // Directory for application state (links, metadata, ...)
 	Samba   struct {
-		ConfigPath string `yaml:"config_path"`
+		ConfigPath       string `yaml:"config_path"`
+		PasswordVerifier string `yaml:"password_verifier"` // "auto" (NT hash with smbclient fallback), "nthash" or "smbclient"
 	} `yaml:"samba"`
 	Server struct {
-		Port string `yaml:"port"`
-		Host string `yaml:"host"`
+		Port           string   `yaml:"port"`
+		Host           string   `yaml:"host"`
+		TrustedProxies []string `yaml:"trusted_proxies"` // Reverse proxies whose X-Forwarded-For is used as the client IP, none by default
 	} `yaml:"server"`
 	JWT struct {
-		Secret string `yaml:"secret"`
+		Secret             string `yaml:"secret"`
+		AccessTokenMinutes int    `yaml:"access_token_minutes"` // Lifetime of access tokens, renewed with the refresh token
+		RefreshTokenDays   int    `yaml:"refresh_token_days"`   // How long a login lasts before signing in again
 	} `yaml:"jwt"`
+	Archive struct {
+		MaxTotalSizeMB int64 `yaml:"max_total_size_mb"` // Maximum uncompressed size of a directory download (0 = unlimited)
+		MaxEntries     int   `yaml:"max_entries"`       // Maximum number of files and folders in a directory download (0 = unlimited)
+	} `yaml:"archive"`
+	OrphanArchive struct {
+		Dir         string `yaml:"dir"`         // Where archived orphaned home directories are stored
+		Compression string `yaml:"compression"` // "gzip" (tar.gz) or "zstd" (tar.zst, requires the zstd command)
+	} `yaml:"orphan_archive"`
+	PasswordPolicy struct {
+		DictionaryFile string `yaml:"dictionary_file"` // Newline separated list of common or breached passwords to reject
+	} `yaml:"password_policy"`
+	LoginProtection struct {
+		BackoffAfter      int `yaml:"backoff_after"`       // Failed logins before each further attempt is delayed exponentially
+		MaxUserFailures   int `yaml:"max_user_failures"`   // Failed logins for one username before it is locked out
+		MaxIPFailures     int `yaml:"max_ip_failures"`     // Failed logins from one IP address before it is locked out
+		WindowMinutes     int `yaml:"window_minutes"`      // Period in which failures are counted
+		LockoutMinutes    int `yaml:"lockout_minutes"`     // Length of the first lockout, doubled for each further one
+		MaxLockoutMinutes int `yaml:"max_lockout_minutes"` // Upper limit of the lockout length
+	} `yaml:"login_protection"`
+	OIDC struct {
+		Enabled       bool                `yaml:"enabled"`
+		Issuer        string              `yaml:"issuer"` // Discovery document at <issuer>/.well-known/openid-configuration
+		ClientID      string              `yaml:"client_id"`
+		ClientSecret  string              `yaml:"client_secret"` // Empty for public clients, PKCE is always used
+		RedirectURL   string              `yaml:"redirect_url"`  // Must point to /api/oidc/callback of this server
+		Scopes        []string            `yaml:"scopes"`
+		UsernameClaim string              `yaml:"username_claim"` // Claim holding the Samba username, must be one users can't edit at the provider
+		StripDomain   bool                `yaml:"strip_domain"`   // Use the part before "@" of the username claim
+		RolesClaim    string              `yaml:"roles_claim"`    // Claim listing groups or roles, dots reach nested claims (e.g. realm_access.roles)
+		RoleMapping   map[string][]string `yaml:"role_mapping"`   // Value of the roles claim to admin roles
+		FrontendPath  string              `yaml:"frontend_path"`  // Web UI page the browser returns to after signing in
+	} `yaml:"oidc"`
 }
 
 var AppConfig *Config
@@ -45,10 +85,25 @@ func createDefaultConfig(configPath string) error {
 	defaultConfig.Admin.Username = "admin"
 	defaultConfig.Admin.Password = "admin"
 	defaultConfig.HomeDir = "/home/samba"
+	defaultConfig.DataDir = "/var/lib/samba-manager"
 	defaultConfig.Samba.ConfigPath = "/etc/samba/smb.conf"
+	defaultConfig.Samba.PasswordVerifier = "auto"
 	defaultConfig.Server.Port = "8080"
 	defaultConfig.Server.Host = "0.0.0.0"
 	defaultConfig.JWT.Secret = generateRandomSecret()
+	defaultConfig.JWT.AccessTokenMinutes = 15
+	defaultConfig.JWT.RefreshTokenDays = 30
+	defaultConfig.Archive.MaxTotalSizeMB = 10240
+	defaultConfig.Archive.MaxEntries = 100000
+	defaultConfig.OrphanArchive.Dir = "/var/lib/samba-manager/orphan-archives"
+	defaultConfig.OrphanArchive.Compression = "gzip"
+	defaultConfig.PasswordPolicy.DictionaryFile = "/var/lib/samba-manager/password-dictionary.txt"
+	defaultConfig.LoginProtection.BackoffAfter = 3
+	defaultConfig.LoginProtection.MaxUserFailures = 10
+	defaultConfig.LoginProtection.MaxIPFailures = 30
+	defaultConfig.LoginProtection.WindowMinutes = 15
+	defaultConfig.LoginProtection.LockoutMinutes = 15
+	defaultConfig.LoginProtection.MaxLockoutMinutes = 24 * 60
 
 	data, err := yaml.Marshal(defaultConfig)
 	if err != nil {
@@ -79,6 +134,9 @@ func Load(configPath string) error {
 	}
 
 	var cfg Config
+	// Set before parsing, as 0 in the file means unlimited and must not be replaced by a default
+	cfg.Archive.MaxTotalSizeMB = 10240
+	cfg.Archive.MaxEntries = 100000
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return fmt.Errorf("failed to parse config file: %w", err)
 	}
@@ -88,10 +146,155 @@ func Load(configPath string) error {
 		return fmt.Errorf("JWT secret is missing in config file. Please regenerate config or add a secret")
 	}
 
+	applyDefaults(&cfg)
+
+	switch cfg.Samba.PasswordVerifier {
+	case "auto", "nthash", "smbclient":
+	default:
+		return fmt.Errorf("unknown samba.password_verifier %q, use auto, nthash or smbclient", cfg.Samba.PasswordVerifier)
+	}
+
+	if err := validateOIDC(&cfg); err != nil {
+		return err
+	}
+
 	AppConfig = &cfg
 	return nil
 }
 
+// validateOIDC checks the single sign-on settings when they are enabled
+func validateOIDC(cfg *Config) error {
+	if !cfg.OIDC.Enabled {
+		return nil
+	}
+	if cfg.OIDC.Issuer == "" || cfg.OIDC.ClientID == "" || cfg.OIDC.RedirectURL == "" {
+		return fmt.Errorf("oidc.issuer, oidc.client_id and oidc.redirect_url are required when OIDC is enabled")
+	}
+	// No default: claims like preferred_username are not guaranteed to be unique or stable (OIDC Core 5.7)
+	if cfg.OIDC.UsernameClaim == "" {
+		return fmt.Errorf("oidc.username_claim is required when OIDC is enabled, use a claim users can't change at the identity provider")
+	}
+
+	knownRoles := map[string]bool{"admin": true, "user-manager": true, "share-manager": true, "auditor": true}
+	for value, roles := range cfg.OIDC.RoleMapping {
+		for _, role := range roles {
+			if !knownRoles[role] {
+				return fmt.Errorf("unknown admin role %q in oidc.role_mapping.%s", role, value)
+			}
+		}
+	}
+	return nil
+}
+
+// applyDefaults fills in settings missing from older configuration files
+func applyDefaults(cfg *Config) {
+	if cfg.DataDir == "" {
+		cfg.DataDir = "/var/lib/samba-manager"
+	}
+	if cfg.Samba.PasswordVerifier == "" {
+		cfg.Samba.PasswordVerifier = "auto"
+	}
+	if cfg.JWT.AccessTokenMinutes <= 0 {
+		cfg.JWT.AccessTokenMinutes = 15
+	}
+	if cfg.JWT.RefreshTokenDays <= 0 {
+		cfg.JWT.RefreshTokenDays = 30
+	}
+	if cfg.OrphanArchive.Dir == "" {
+		cfg.OrphanArchive.Dir = filepath.Join(cfg.DataDir, "orphan-archives")
+	}
+	if cfg.OrphanArchive.Compression == "" {
+		cfg.OrphanArchive.Compression = "gzip"
+	}
+	if cfg.PasswordPolicy.DictionaryFile == "" {
+		cfg.PasswordPolicy.DictionaryFile = filepath.Join(cfg.DataDir, "password-dictionary.txt")
+	}
+	if len(cfg.OIDC.Scopes) == 0 {
+		cfg.OIDC.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.OIDC.FrontendPath == "" {
+		cfg.OIDC.FrontendPath = "/login/sso"
+	}
+	if cfg.LoginProtection.BackoffAfter <= 0 {
+		cfg.LoginProtection.BackoffAfter = 3
+	}
+	if cfg.LoginProtection.MaxUserFailures <= 0 {
+		cfg.LoginProtection.MaxUserFailures = 10
+	}
+	if cfg.LoginProtection.MaxIPFailures <= 0 {
+		cfg.LoginProtection.MaxIPFailures = 30
+	}
+	if cfg.LoginProtection.WindowMinutes <= 0 {
+		cfg.LoginProtection.WindowMinutes = 15
+	}
+	if cfg.LoginProtection.LockoutMinutes <= 0 {
+		cfg.LoginProtection.LockoutMinutes = 15
+	}
+	if cfg.LoginProtection.MaxLockoutMinutes < cfg.LoginProtection.LockoutMinutes {
+		cfg.LoginProtection.MaxLockoutMinutes = max(24*60, cfg.LoginProtection.LockoutMinutes)
+	}
+}
+
+// ClearAdminPassword removes the plaintext admin password from the configuration file
+// The file is edited as a YAML tree so comments and the order of settings are kept
+func ClearAdminPassword(configPath string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	password := findYAMLValue(&doc, "admin", "password")
+	if password == nil || password.Value == "" {
+		return nil
+	}
+	password.Value = ""
+	password.Style = yaml.DoubleQuotedStyle
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.WriteFile(configPath, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if AppConfig != nil {
+		AppConfig.Admin.Password = ""
+	}
+	return nil
+}
+
+// findYAMLValue returns the value node at a path of mapping keys, or nil
+func findYAMLValue(node *yaml.Node, path ...string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
 // GetJWTSecret returns the JWT secret key
 func GetJWTSecret() []byte {
 	if AppConfig == nil || AppConfig.JWT.Secret == "" {
@@ -100,3 +303,13 @@ func GetJWTSecret() []byte {
 	}
 	return []byte(AppConfig.JWT.Secret)
 }
+
+// GetAccessTokenLifetime returns how long an access token is valid
+func GetAccessTokenLifetime() time.Duration {
+	return time.Duration(AppConfig.JWT.AccessTokenMinutes) * time.Minute
+}
+
+// GetRefreshTokenLifetime returns how long a login can be renewed with refresh tokens
+func GetRefreshTokenLifetime() time.Duration {
+	return time.Duration(AppConfig.JWT.RefreshTokenDays) * 24 * time.Hour
+}
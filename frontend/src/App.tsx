@@ -4,9 +4,12 @@ import { ThemeProvider, createTheme } from '@mui/material/styles'
 import CssBaseline from '@mui/material/CssBaseline'
 import { Snackbar, Alert } from '@mui/material'
 import { Login } from './pages/Login'
+import { SsoLogin } from './pages/SsoLogin'
 import { Dashboard } from './pages/Dashboard'
 import { UserDashboard } from './pages/UserDashboard'
+import { TwoFactor } from './pages/TwoFactor'
 import { setGlobalHandlers } from './utils/handleResp'
+import { clearSession } from './utils/session'
 
 const theme = createTheme({
   palette: {
@@ -19,6 +22,17 @@ const theme = createTheme({
   },
 })
 
+// Route component for any signed-in account, including one that still has to set up two-factor authentication
+function AuthRoute({ children }: { children: React.ReactNode }) {
+  const token = localStorage.getItem('token')
+
+  if (!token) {
+    return <Navigate to="/login" replace />
+  }
+
+  return <>{children}</>
+}
+
 // Admin-only route component
 function AdminRoute({ children }: { children: React.ReactNode }) {
   const token = localStorage.getItem('token')
@@ -28,6 +42,10 @@ function AdminRoute({ children }: { children: React.ReactNode }) {
     return <Navigate to="/login" replace />
   }
 
+  if (localStorage.getItem('two_factor_setup_required')) {
+    return <Navigate to="/two-factor" replace />
+  }
+
   if (role !== 'admin') {
     return <Navigate to="/user-dashboard" replace />
   }
@@ -44,6 +62,10 @@ function UserRoute({ children }: { children: React.ReactNode }) {
     return <Navigate to="/login" replace />
   }
 
+  if (localStorage.getItem('two_factor_setup_required')) {
+    return <Navigate to="/two-factor" replace />
+  }
+
   if (role !== 'user') {
     return <Navigate to="/dashboard" replace />
   }
@@ -62,12 +84,6 @@ function AppContent() {
 
   useEffect(() => {
     // Set up global handlers for handleResp
-    const logout = () => {
-      localStorage.removeItem('token')
-      localStorage.removeItem('role')
-      localStorage.removeItem('username')
-    }
-
     const navigateToLogin = (path: string) => {
       navigate(path)
     }
@@ -76,7 +92,7 @@ function AppContent() {
       setSnackbar({ open: true, message, severity })
     }
 
-    setGlobalHandlers(logout, navigateToLogin, showSnackbar)
+    setGlobalHandlers(clearSession, navigateToLogin, showSnackbar)
   }, [navigate])
 
   const handleCloseSnackbar = () => {
@@ -87,6 +103,7 @@ function AppContent() {
     <>
       <Routes>
         <Route path="/login" element={<Login />} />
+        <Route path="/login/sso" element={<SsoLogin />} />
         <Route
           path="/dashboard/*"
           element={
@@ -103,6 +120,14 @@ function AppContent() {
             </UserRoute>
           }
         />
+        <Route
+          path="/two-factor"
+          element={
+            <AuthRoute>
+              <TwoFactor />
+            </AuthRoute>
+          }
+        />
         <Route path="/" element={<Navigate to="/login" replace />} />
       </Routes>
 
@@ -1,4 +1,38 @@
-import type { LoginRequest, LoginResponse, ApiResponse } from '../types';
+import { api } from './config';
+import type {
+  LoginRequest,
+  LoginResponse,
+  OIDCConfigResponse,
+  OIDCExchangeRequest,
+  TwoFactorLoginRequest,
+  ApiResponse,
+} from '../types';
+
+/**
+ * Post to a login endpoint, which doesn't require authentication
+ */
+const postLogin = async (url: string, body: unknown): Promise<ApiResponse<LoginResponse>> => {
+  // Use fetch directly without auth since it's a login endpoint
+  const headers = new Headers();
+  headers.set('Content-Type', 'application/json');
+
+  try {
+    const response = await fetch(url, {
+      method: 'POST',
+      headers,
+      body: JSON.stringify(body),
+    });
+
+    const data: ApiResponse<LoginResponse> = await response.json();
+    return data;
+  } catch (error) {
+    return {
+      code: 0,
+      message: error instanceof Error ? error.message : 'Login failed',
+      data: undefined,
+    };
+  }
+};
 
 /**
  * Authentication API
@@ -6,28 +40,48 @@ import type { LoginRequest, LoginResponse, ApiResponse } from '../types';
 export const authAPI = {
   /**
    * Login with username and password
-   * Note: Login endpoint doesn't require authentication
+   * Accounts with two-factor authentication get a challenge token for loginTwoFactor instead
    */
   login: async (credentials: LoginRequest): Promise<ApiResponse<LoginResponse>> => {
-    // Use api.post directly without auth since it's the login endpoint
-    const headers = new Headers();
-    headers.set('Content-Type', 'application/json');
+    return postLogin('/api/login', credentials);
+  },
 
-    try {
-      const response = await fetch('/api/login', {
-        method: 'POST',
-        headers,
-        body: JSON.stringify(credentials),
-      });
+  /**
+   * Finish a login with a TOTP or recovery code
+   */
+  loginTwoFactor: async (request: TwoFactorLoginRequest): Promise<ApiResponse<LoginResponse>> => {
+    return postLogin('/api/login/two-factor', request);
+  },
 
-      const data: ApiResponse<LoginResponse> = await response.json();
+  /**
+   * Check whether single sign-on is enabled
+   * Note: doesn't require authentication, the login page asks before anyone signed in
+   */
+  getOIDCConfig: async (): Promise<ApiResponse<OIDCConfigResponse>> => {
+    try {
+      const response = await fetch('/api/oidc/config');
+      const data: ApiResponse<OIDCConfigResponse> = await response.json();
       return data;
     } catch (error) {
       return {
         code: 0,
-        message: error instanceof Error ? error.message : 'Login failed',
+        message: error instanceof Error ? error.message : 'Failed to load single sign-on settings',
         data: undefined,
       };
     }
   },
+
+  /**
+   * Exchange the one-time code from the single sign-on callback for tokens
+   */
+  exchangeOIDCCode: async (request: OIDCExchangeRequest): Promise<ApiResponse<LoginResponse>> => {
+    return postLogin('/api/oidc/exchange', request);
+  },
+
+  /**
+   * End the current session on the server, its refresh token stops working
+   */
+  logout: async (): Promise<ApiResponse<void>> => {
+    return api.post<void>('/logout');
+  },
 };
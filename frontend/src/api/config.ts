@@ -1,7 +1,59 @@
-import type { ApiResponse, PaginatedResponse } from '../types';
+import type { ApiResponse, LoginResponse, PaginatedResponse } from '../types';
+import { saveSession } from '../utils/session';
 
 const API_BASE_URL = '/api';
 
+// Refresh in progress, shared by all requests that found the access token expired
+let refreshInProgress: Promise<boolean> | null = null;
+
+/**
+ * Renew the access token with the stored refresh token
+ * Refresh tokens are single-use, so concurrent requests wait for the same refresh
+ *
+ * @returns Whether a new access token was stored
+ */
+const refreshAccessToken = (): Promise<boolean> => {
+  if (!refreshInProgress) {
+    refreshInProgress = (async () => {
+      const refreshToken = localStorage.getItem('refresh_token');
+      if (!refreshToken) {
+        return false;
+      }
+
+      try {
+        const response = await fetch(`${API_BASE_URL}/token/refresh`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        const data: ApiResponse<LoginResponse> = await response.json();
+        if (data.code !== 200 || !data.data) {
+          // The login ended, the caller's 401 leads back to the login page
+          localStorage.removeItem('refresh_token');
+          return false;
+        }
+
+        saveSession(data.data);
+        return true;
+      } catch {
+        return false;
+      }
+    })().finally(() => {
+      refreshInProgress = null;
+    });
+  }
+
+  return refreshInProgress;
+};
+
+/**
+ * Whether a response rejected the access token itself (expired or invalid),
+ * as opposed to e.g. a wrong current password, which must not be retried
+ */
+const isExpiredTokenResponse = (response: Response): boolean => {
+  return response.status === 401 && (response.headers.get('WWW-Authenticate') ?? '').includes('invalid_token');
+};
+
 /**
  * Enhanced fetch wrapper with interceptors and error handling
  *
@@ -32,7 +84,13 @@ const fetchWithInterceptors = async <T>(
   };
 
   try {
-    const response = await fetch(url, config);
+    let response = await fetch(url, config);
+
+    // Response interceptor - renew an expired access token once and retry
+    if (requiresAuth && isExpiredTokenResponse(response) && await refreshAccessToken()) {
+      headers.set('Authorization', `Bearer ${localStorage.getItem('token')}`);
+      response = await fetch(url, config);
+    }
 
     // Try to parse JSON response
     try {
@@ -5,4 +5,5 @@ export { shareAPI } from './shares';
 export { userShareAPI } from './userShares';
 export { userProfileAPI } from './userProfile';
 export { systemAPI } from './system';
+export { twoFactorAPI } from './twoFactor';
 export { api, callApi } from './config';
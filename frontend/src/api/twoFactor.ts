@@ -0,0 +1,48 @@
+import { api } from './config';
+import type {
+  ApiResponse,
+  TwoFactorStatus,
+  TwoFactorSetupResponse,
+  TwoFactorConfirmResponse,
+  TwoFactorRecoveryCodesResponse,
+} from '../types';
+
+/**
+ * Two-Factor Authentication API (for current logged-in user)
+ */
+export const twoFactorAPI = {
+  /**
+   * Get the two-factor state of the current account
+   */
+  getStatus: async (): Promise<ApiResponse<TwoFactorStatus>> => {
+    return api.get<TwoFactorStatus>('/user/two-factor');
+  },
+
+  /**
+   * Generate a new secret to add to an authenticator app
+   */
+  beginSetup: async (): Promise<ApiResponse<TwoFactorSetupResponse>> => {
+    return api.post<TwoFactorSetupResponse>('/user/two-factor/setup');
+  },
+
+  /**
+   * Enable two-factor authentication with a first code, returns the recovery codes
+   */
+  confirmSetup: async (code: string): Promise<ApiResponse<TwoFactorConfirmResponse>> => {
+    return api.post<TwoFactorConfirmResponse>('/user/two-factor/confirm', { code });
+  },
+
+  /**
+   * Replace the recovery codes
+   */
+  regenerateRecoveryCodes: async (code: string): Promise<ApiResponse<TwoFactorRecoveryCodesResponse>> => {
+    return api.post<TwoFactorRecoveryCodesResponse>('/user/two-factor/recovery-codes', { code });
+  },
+
+  /**
+   * Turn off two-factor authentication, unless the policy requires it
+   */
+  disable: async (code: string): Promise<ApiResponse<void>> => {
+    return api.delete<void>('/user/two-factor', { code });
+  },
+};
@@ -17,10 +17,13 @@ import {
   Settings as SettingsIcon,
   Language as LanguageIcon,
   Logout as LogoutIcon,
+  Security as SecurityIcon,
 } from '@mui/icons-material';
 import { UserManagement } from './UserManagement';
 import { ShareManagement } from './ShareManagement';
 import { Settings } from './Settings';
+import { authAPI } from '../api';
+import { clearSession } from '../utils/session';
 
 export function Dashboard() {
   const navigate = useNavigate();
@@ -36,8 +39,9 @@ export function Dashboard() {
     return 0;
   };
 
-  const handleLogout = () => {
-    localStorage.removeItem('token');
+  const handleLogout = async () => {
+    await authAPI.logout();
+    clearSession();
     navigate('/login');
     window.location.reload();
   };
@@ -62,6 +66,13 @@ export function Dashboard() {
           <IconButton color="inherit" onClick={toggleLanguage} title="Switch Language">
             <LanguageIcon />
           </IconButton>
+          <Button
+            color="inherit"
+            startIcon={<SecurityIcon />}
+            onClick={() => navigate('/two-factor')}
+          >
+            {t('twoFactor.title')}
+          </Button>
           <Button
             color="inherit"
             startIcon={<LogoutIcon />}
@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react';
-import { useNavigate } from 'react-router-dom';
+import { useNavigate, useLocation } from 'react-router-dom';
 import { useTranslation } from 'react-i18next';
 import {
   Box,
@@ -11,18 +11,39 @@ import {
   TextField,
   Typography,
   Alert,
+  Divider,
 } from '@mui/material';
 import { Language as LanguageIcon } from '@mui/icons-material';
 import { authAPI } from '../api';
 import { handleRespWithoutAuthAndNotify } from '../utils/handleResp';
+import { saveSession } from '../utils/session';
+import type { LoginResponse } from '../types';
 
 export function Login() {
   const navigate = useNavigate();
+  const location = useLocation();
   const { t, i18n } = useTranslation();
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  // A single sign-on of an account with two-factor authentication continues here with its challenge
+  const [challengeToken, setChallengeToken] = useState<string>(
+    (location.state as { challengeToken?: string } | null)?.challengeToken || ''
+  );
+  const [code, setCode] = useState('');
+  const [ssoEnabled, setSsoEnabled] = useState(false);
+
+  // Offer single sign-on if an identity provider is configured
+  useEffect(() => {
+    const loadOIDCConfig = async () => {
+      const resp = await authAPI.getOIDCConfig();
+      handleRespWithoutAuthAndNotify(resp, (data) => {
+        setSsoEnabled(data.enabled);
+      });
+    };
+    void loadOIDCConfig();
+  }, []);
 
   // Check if user is already logged in
   useEffect(() => {
@@ -30,8 +51,10 @@ export function Login() {
     const role = localStorage.getItem('role');
 
     if (token && role) {
-      // Redirect based on role
-      if (role === 'admin') {
+      // Redirect to a pending required setup, otherwise based on role
+      if (localStorage.getItem('two_factor_setup_required')) {
+        navigate('/two-factor', { replace: true });
+      } else if (role === 'admin') {
         navigate('/dashboard/user-management', { replace: true });
       } else {
         navigate('/user-dashboard', { replace: true });
@@ -39,6 +62,20 @@ export function Login() {
     }
   }, [navigate]);
 
+  const completeLogin = (data: LoginResponse) => {
+    // Save tokens and role to localStorage
+    saveSession(data);
+
+    // A setup required by the policy comes first, otherwise navigate based on role
+    if (data.two_factor_setup_required) {
+      navigate('/two-factor');
+    } else if (data.role === 'admin') {
+      navigate('/dashboard/user-management');
+    } else {
+      navigate('/user-dashboard');
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setError('');
@@ -48,17 +85,31 @@ export function Login() {
     handleRespWithoutAuthAndNotify(
       resp,
       (data) => {
-        // Save token and role to localStorage
-        localStorage.setItem('token', data.token);
-        localStorage.setItem('role', data.role);
-        localStorage.setItem('username', data.username);
-
-        // Navigate based on role
-        if (data.role === 'admin') {
-          navigate('/dashboard/user-management');
-        } else {
-          navigate('/user-dashboard');
+        // Accounts with two-factor authentication continue with a code
+        if (data.two_factor_required && data.challenge_token) {
+          setChallengeToken(data.challenge_token);
+          setPassword('');
+          return;
         }
+        completeLogin(data);
+      },
+      (message) => {
+        setError(message);
+      }
+    );
+    setLoading(false);
+  };
+
+  const handleSubmitCode = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setError('');
+    setLoading(true);
+
+    const resp = await authAPI.loginTwoFactor({ challenge_token: challengeToken, code: code.trim() });
+    handleRespWithoutAuthAndNotify(
+      resp,
+      (data) => {
+        completeLogin(data);
       },
       (message) => {
         setError(message);
@@ -67,6 +118,12 @@ export function Login() {
     setLoading(false);
   };
 
+  const handleBackToPassword = () => {
+    setChallengeToken('');
+    setCode('');
+    setError('');
+  };
+
   const toggleLanguage = () => {
     const newLang = i18n.language === 'zh' ? 'en' : 'zh';
     i18n.changeLanguage(newLang);
@@ -105,46 +162,107 @@ export function Login() {
               {t('login.subtitle')}
             </Typography>
             
-            <form onSubmit={handleSubmit}>
-              <TextField
-                fullWidth
-                label={t('login.username')}
-                variant="outlined"
-                margin="normal"
-                value={username}
-                onChange={(e) => setUsername(e.target.value)}
-                required
-                disabled={loading}
-              />
-              <TextField
-                fullWidth
-                label={t('login.password')}
-                type="password"
-                variant="outlined"
-                margin="normal"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-                disabled={loading}
-              />
-              
-              {error && (
-                <Alert severity="error" sx={{ mt: 2 }}>
-                  {error}
-                </Alert>
-              )}
-              
-              <Button
-                type="submit"
-                fullWidth
-                variant="contained"
-                size="large"
-                disabled={loading}
-                sx={{ mt: 3 }}
-              >
-                {loading ? t('login.loggingIn') : t('login.loginButton')}
-              </Button>
-            </form>
+            {challengeToken ? (
+              <form onSubmit={handleSubmitCode}>
+                <Typography variant="body2" sx={{ mb: 1 }}>
+                  {t('login.twoFactorPrompt')}
+                </Typography>
+                <TextField
+                  fullWidth
+                  label={t('login.twoFactorCode')}
+                  helperText={t('login.twoFactorCodeHelper')}
+                  variant="outlined"
+                  margin="normal"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  required
+                  disabled={loading}
+                  autoFocus
+                  autoComplete="one-time-code"
+                />
+
+                {error && (
+                  <Alert severity="error" sx={{ mt: 2 }}>
+                    {error}
+                  </Alert>
+                )}
+
+                <Button
+                  type="submit"
+                  fullWidth
+                  variant="contained"
+                  size="large"
+                  disabled={loading}
+                  sx={{ mt: 3 }}
+                >
+                  {loading ? t('login.verifying') : t('login.verifyButton')}
+                </Button>
+                <Button
+                  fullWidth
+                  onClick={handleBackToPassword}
+                  disabled={loading}
+                  sx={{ mt: 1 }}
+                >
+                  {t('login.backToPassword')}
+                </Button>
+              </form>
+            ) : (
+              <form onSubmit={handleSubmit}>
+                <TextField
+                  fullWidth
+                  label={t('login.username')}
+                  variant="outlined"
+                  margin="normal"
+                  value={username}
+                  onChange={(e) => setUsername(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+                <TextField
+                  fullWidth
+                  label={t('login.password')}
+                  type="password"
+                  variant="outlined"
+                  margin="normal"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+
+                {error && (
+                  <Alert severity="error" sx={{ mt: 2 }}>
+                    {error}
+                  </Alert>
+                )}
+
+                <Button
+                  type="submit"
+                  fullWidth
+                  variant="contained"
+                  size="large"
+                  disabled={loading}
+                  sx={{ mt: 3 }}
+                >
+                  {loading ? t('login.loggingIn') : t('login.loginButton')}
+                </Button>
+
+                {ssoEnabled && (
+                  <>
+                    <Divider sx={{ my: 3 }}>{t('login.or')}</Divider>
+                    <Button
+                      fullWidth
+                      variant="outlined"
+                      size="large"
+                      href="/api/oidc/login"
+                      disabled={loading}
+                    >
+                      {t('login.ssoButton')}
+                    </Button>
+                  </>
+                )}
+              </form>
+            )}
           </CardContent>
         </Card>
       </Container>
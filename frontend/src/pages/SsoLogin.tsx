@@ -0,0 +1,108 @@
+import { useState, useEffect, useRef } from 'react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import { useTranslation } from 'react-i18next';
+import {
+  Alert,
+  Box,
+  Button,
+  Card,
+  CardContent,
+  CircularProgress,
+  Container,
+  Typography,
+} from '@mui/material';
+import { authAPI } from '../api';
+import { handleRespWithoutAuthAndNotify } from '../utils/handleResp';
+import { saveSession } from '../utils/session';
+
+// Landing page of the single sign-on callback, exchanges its one-time code for tokens
+export function SsoLogin() {
+  const navigate = useNavigate();
+  const { t } = useTranslation();
+  const [searchParams] = useSearchParams();
+  const [error, setError] = useState(searchParams.get('error') || '');
+  const exchanged = useRef(false);
+
+  useEffect(() => {
+    const code = searchParams.get('code');
+    // The code works once, StrictMode must not send it twice
+    if (!code || exchanged.current) {
+      return;
+    }
+    exchanged.current = true;
+
+    const exchange = async () => {
+      const resp = await authAPI.exchangeOIDCCode({ code });
+      handleRespWithoutAuthAndNotify(
+        resp,
+        (data) => {
+          // Accounts with two-factor authentication enter their code on the login page
+          if (data.two_factor_required && data.challenge_token) {
+            navigate('/login', { replace: true, state: { challengeToken: data.challenge_token } });
+            return;
+          }
+
+          saveSession(data);
+          if (data.two_factor_setup_required) {
+            navigate('/two-factor', { replace: true });
+          } else if (data.role === 'admin') {
+            navigate('/dashboard/user-management', { replace: true });
+          } else {
+            navigate('/user-dashboard', { replace: true });
+          }
+        },
+        (message) => {
+          setError(message);
+        }
+      );
+    };
+    void exchange();
+  }, [navigate, searchParams]);
+
+  const missingCode = !error && !searchParams.get('code');
+
+  return (
+    <Box
+      sx={{
+        minHeight: '100vh',
+        display: 'flex',
+        alignItems: 'center',
+        justifyContent: 'center',
+        bgcolor: 'grey.100',
+      }}
+    >
+      <Container maxWidth="sm">
+        <Card elevation={3}>
+          <CardContent sx={{ p: 4 }}>
+            <Typography variant="h5" component="h1" gutterBottom align="center">
+              {t('login.ssoTitle')}
+            </Typography>
+
+            {error || missingCode ? (
+              <>
+                <Alert severity="error" sx={{ mt: 2 }}>
+                  {error || t('login.ssoFailed')}
+                </Alert>
+                <Button
+                  fullWidth
+                  variant="contained"
+                  onClick={() => navigate('/login', { replace: true })}
+                  sx={{ mt: 3 }}
+                >
+                  {t('login.backToLogin')}
+                </Button>
+              </>
+            ) : (
+              <Box sx={{ display: 'flex', flexDirection: 'column', alignItems: 'center', mt: 2 }}>
+                <CircularProgress />
+                <Typography variant="body2" color="text.secondary" sx={{ mt: 2 }}>
+                  {t('login.ssoSigningIn')}
+                </Typography>
+              </Box>
+            )}
+          </CardContent>
+        </Card>
+      </Container>
+    </Box>
+  );
+}
@@ -0,0 +1,240 @@
+import { useState, useEffect } from 'react';
+import { useNavigate } from 'react-router-dom';
+import { useTranslation } from 'react-i18next';
+import {
+  Alert,
+  Box,
+  Button,
+  Card,
+  CardContent,
+  Chip,
+  Container,
+  Paper,
+  TextField,
+  Typography,
+} from '@mui/material';
+import { authAPI, twoFactorAPI } from '../api';
+import { handleResp, handleRespWithNotifySuccess } from '../utils/handleResp';
+import { clearSession, saveSession } from '../utils/session';
+import type { TwoFactorStatus, TwoFactorSetupResponse } from '../types';
+
+export function TwoFactor() {
+  const navigate = useNavigate();
+  const { t } = useTranslation();
+  const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+  const [setup, setSetup] = useState<TwoFactorSetupResponse | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [code, setCode] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  // A token restricted to the required setup allows nothing else
+  const setupRequired = localStorage.getItem('two_factor_setup_required') === 'true';
+
+  const loadStatus = async () => {
+    const resp = await twoFactorAPI.getStatus();
+    handleResp(resp, (data) => {
+      setStatus(data);
+    });
+  };
+
+  useEffect(() => {
+    loadStatus();
+  }, []);
+
+  const handleBeginSetup = async () => {
+    setLoading(true);
+    const resp = await twoFactorAPI.beginSetup();
+    handleResp(resp, (data) => {
+      setSetup(data);
+      setCode('');
+    });
+    setLoading(false);
+  };
+
+  const handleConfirmSetup = async () => {
+    setLoading(true);
+    const resp = await twoFactorAPI.confirmSetup(code.trim());
+    handleRespWithNotifySuccess(resp, (data) => {
+      // The restricted token was swapped for a full login
+      if (data.login) {
+        saveSession(data.login);
+      }
+      setSetup(null);
+      setCode('');
+      setRecoveryCodes(data.recovery_codes);
+      loadStatus();
+    });
+    setLoading(false);
+  };
+
+  const handleRegenerateRecoveryCodes = async () => {
+    setLoading(true);
+    const resp = await twoFactorAPI.regenerateRecoveryCodes(code.trim());
+    handleRespWithNotifySuccess(resp, (data) => {
+      setCode('');
+      setRecoveryCodes(data.recovery_codes);
+      loadStatus();
+    });
+    setLoading(false);
+  };
+
+  const handleDisable = async () => {
+    setLoading(true);
+    const resp = await twoFactorAPI.disable(code.trim());
+    handleRespWithNotifySuccess(resp, () => {
+      setCode('');
+      setRecoveryCodes([]);
+      loadStatus();
+    });
+    setLoading(false);
+  };
+
+  const handleContinue = () => {
+    if (localStorage.getItem('role') === 'admin') {
+      navigate('/dashboard/user-management');
+    } else {
+      navigate('/user-dashboard');
+    }
+  };
+
+  const handleLogout = async () => {
+    await authAPI.logout();
+    clearSession();
+    navigate('/login');
+  };
+
+  return (
+    <Box
+      sx={{
+        minHeight: '100vh',
+        display: 'flex',
+        alignItems: 'center',
+        justifyContent: 'center',
+        bgcolor: 'grey.100',
+      }}
+    >
+      <Container maxWidth="sm">
+        <Card elevation={3}>
+          <CardContent sx={{ p: 4 }}>
+            <Typography variant="h5" component="h1" gutterBottom>
+              {t('twoFactor.title')}
+            </Typography>
+
+            {setupRequired && (
+              <Alert severity="info" sx={{ mb: 2 }}>
+                {t('twoFactor.setupRequired')}
+              </Alert>
+            )}
+
+            {recoveryCodes.length > 0 ? (
+              <Box>
+                <Alert severity="warning" sx={{ mb: 2 }}>
+                  {t('twoFactor.recoveryCodesInfo')}
+                </Alert>
+                <Paper variant="outlined" sx={{ p: 2, mb: 2 }}>
+                  {recoveryCodes.map((recoveryCode) => (
+                    <Typography key={recoveryCode} sx={{ fontFamily: 'monospace' }}>
+                      {recoveryCode}
+                    </Typography>
+                  ))}
+                </Paper>
+                <Button fullWidth variant="contained" onClick={handleContinue}>
+                  {t('twoFactor.continue')}
+                </Button>
+              </Box>
+            ) : setup ? (
+              <Box>
+                <Typography variant="body2" sx={{ mb: 2 }}>
+                  {t('twoFactor.scanInfo')}
+                </Typography>
+                <Box sx={{ display: 'flex', justifyContent: 'center', mb: 2 }}>
+                  <img src={setup.qr_code} alt={t('twoFactor.qrCode')} width={200} height={200} />
+                </Box>
+                <Typography variant="body2" color="text.secondary">
+                  {t('twoFactor.secret')}
+                </Typography>
+                <Typography sx={{ fontFamily: 'monospace', wordBreak: 'break-all', mb: 2 }}>
+                  {setup.secret}
+                </Typography>
+                <TextField
+                  fullWidth
+                  label={t('twoFactor.code')}
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  disabled={loading}
+                  autoComplete="one-time-code"
+                />
+                <Button
+                  fullWidth
+                  variant="contained"
+                  onClick={handleConfirmSetup}
+                  disabled={loading || !code.trim()}
+                  sx={{ mt: 2 }}
+                >
+                  {t('twoFactor.enable')}
+                </Button>
+              </Box>
+            ) : status && !status.enabled ? (
+              <Box>
+                <Typography variant="body2" sx={{ mb: 2 }}>
+                  {t('twoFactor.disabledInfo')}
+                </Typography>
+                <Button fullWidth variant="contained" onClick={handleBeginSetup} disabled={loading}>
+                  {t('twoFactor.setUp')}
+                </Button>
+              </Box>
+            ) : status ? (
+              <Box>
+                <Box sx={{ display: 'flex', gap: 1, mb: 2 }}>
+                  <Chip label={t('twoFactor.enabled')} color="success" size="small" />
+                  {status.required && <Chip label={t('twoFactor.required')} size="small" />}
+                </Box>
+                <Typography variant="body2" sx={{ mb: 2 }}>
+                  {t('twoFactor.recoveryCodesLeft', { count: status.recovery_codes_left })}
+                </Typography>
+                <TextField
+                  fullWidth
+                  label={t('twoFactor.code')}
+                  helperText={t('twoFactor.codeHelper')}
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  disabled={loading}
+                  autoComplete="one-time-code"
+                />
+                <Box sx={{ display: 'flex', gap: 1, mt: 2 }}>
+                  <Button
+                    variant="outlined"
+                    onClick={handleRegenerateRecoveryCodes}
+                    disabled={loading || !code.trim()}
+                  >
+                    {t('twoFactor.newRecoveryCodes')}
+                  </Button>
+                  {!status.required && (
+                    <Button
+                      variant="outlined"
+                      color="error"
+                      onClick={handleDisable}
+                      disabled={loading || !code.trim()}
+                    >
+                      {t('twoFactor.disable')}
+                    </Button>
+                  )}
+                </Box>
+              </Box>
+            ) : null}
+
+            {recoveryCodes.length === 0 && (
+              <Box sx={{ display: 'flex', justifyContent: 'flex-end', mt: 3 }}>
+                {setupRequired ? (
+                  <Button onClick={handleLogout}>{t('common.logout')}</Button>
+                ) : (
+                  <Button onClick={handleContinue}>{t('twoFactor.back')}</Button>
+                )}
+              </Box>
+            )}
+          </CardContent>
+        </Card>
+      </Container>
+    </Box>
+  );
+}
@@ -35,9 +35,11 @@ import {
   Logout as LogoutIcon,
   VpnKey as VpnKeyIcon,
   Language as LanguageIcon,
+  Security as SecurityIcon,
 } from '@mui/icons-material';
-import { userShareAPI, userProfileAPI } from '../api';
+import { authAPI, userShareAPI, userProfileAPI } from '../api';
 import { handleResp, handleRespWithNotifySuccess } from '../utils/handleResp';
+import { clearSession } from '../utils/session';
 import type { ShareResponse, CreateMyShareRequest, UpdateShareRequest, UserResponse, ChangeOwnPasswordRequest } from '../types';
 
 export function UserDashboard() {
@@ -201,10 +203,9 @@ export function UserDashboard() {
     );
   };
 
-  const handleLogout = () => {
-    localStorage.removeItem('token');
-    localStorage.removeItem('role');
-    localStorage.removeItem('username');
+  const handleLogout = async () => {
+    await authAPI.logout();
+    clearSession();
     navigate('/login');
   };
 
@@ -231,6 +232,9 @@ export function UserDashboard() {
           <Button color="inherit" startIcon={<VpnKeyIcon />} onClick={handleOpenPasswordDialog}>
             {t('userDashboard.changePassword')}
           </Button>
+          <Button color="inherit" startIcon={<SecurityIcon />} onClick={() => navigate('/two-factor')}>
+            {t('twoFactor.title')}
+          </Button>
           <Button color="inherit" startIcon={<LogoutIcon />} onClick={handleLogout}>
             {t('common.logout')}
           </Button>
@@ -88,10 +88,56 @@ export interface LoginRequest {
 }
 
 export interface LoginResponse {
-  token: string;
+  token?: string;                      // Missing while a two-factor code is still required
+  refresh_token?: string;              // Single-use, exchanged at /api/token/refresh for new tokens
   username: string;
   role: UserRole;
   expires_at: number;
+  refresh_expires_at?: number;
+  two_factor_setup_required?: boolean; // The token only allows setting up two-factor authentication
+  two_factor_required?: boolean;       // Send a code with the challenge token to finish the login
+  challenge_token?: string;
+}
+
+export interface RefreshTokenRequest {
+  refresh_token: string;
+}
+
+export interface OIDCConfigResponse {
+  enabled: boolean;                    // Whether single sign-on is offered on the login page
+}
+
+export interface OIDCExchangeRequest {
+  code: string;                        // One-time code from the single sign-on callback
+}
+
+export interface TwoFactorLoginRequest {
+  challenge_token: string;
+  code: string;                        // TOTP or recovery code
+}
+
+// ===== Two-Factor Authentication Types =====
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  required: boolean;                   // Required by the policy, can't be turned off
+  confirmed_at?: string;
+  recovery_codes_left: number;
+  setup_pending: boolean;
+}
+
+export interface TwoFactorSetupResponse {
+  secret: string;
+  provisioning_uri: string;
+  qr_code: string;                     // PNG data URI of the provisioning URI
+}
+
+export interface TwoFactorRecoveryCodesResponse {
+  recovery_codes: string[];
+}
+
+export interface TwoFactorConfirmResponse extends TwoFactorRecoveryCodesResponse {
+  login?: LoginResponse;               // Full login replacing a token restricted to the setup
 }
 
 // ===== API Response Types =====
@@ -0,0 +1,35 @@
+import type { LoginResponse } from '../types';
+
+/**
+ * Store the tokens and account of a successful login or token refresh
+ */
+export const saveSession = (data: LoginResponse) => {
+  localStorage.setItem('token', data.token ?? '');
+  localStorage.setItem('role', data.role);
+  localStorage.setItem('username', data.username);
+
+  // Until the required setup is done, only the two-factor page works
+  if (data.two_factor_setup_required) {
+    localStorage.setItem('two_factor_setup_required', 'true');
+  } else {
+    localStorage.removeItem('two_factor_setup_required');
+  }
+
+  // Restricted tokens (e.g. for a required password change) can't be refreshed
+  if (data.refresh_token) {
+    localStorage.setItem('refresh_token', data.refresh_token);
+  } else {
+    localStorage.removeItem('refresh_token');
+  }
+};
+
+/**
+ * Forget the tokens and account of the current login
+ */
+export const clearSession = () => {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('role');
+  localStorage.removeItem('username');
+  localStorage.removeItem('two_factor_setup_required');
+};
@@ -44,18 +44,40 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Hash the plaintext admin password from the config file
+	if err := services.MigrateAdminAccounts(*configPath); err != nil {
+		log.Fatalf("Failed to migrate admin account: %v", err)
+	}
+
 	// Initialize queue for request handling
 	taskQueue := queue.NewQueue(1) // 1 worker thread
 
 	// Initialize services
 	sambaService := services.NewSambaService()
+	linkService := services.NewLinkService(sambaService)
+	notificationService := services.NewNotificationService()
+	orphanRetentionService := services.NewOrphanRetentionService(sambaService, notificationService, taskQueue)
+	passwordResetService := services.NewPasswordResetService(sambaService)
+	adminService := services.NewAdminService(sambaService)
+	oidcService := services.NewOIDCService()
 
 	// Initialize handlers (all using the same queue and service for thread safety)
-	userHandler := handlers.NewUserHandler(sambaService, taskQueue)
+	userHandler := handlers.NewUserHandler(sambaService, linkService, taskQueue)
 	shareHandler := handlers.NewShareHandler(sambaService, taskQueue)
 	userShareHandler := handlers.NewUserShareHandler(sambaService, taskQueue)
 	userProfileHandler := handlers.NewUserProfileHandler(sambaService, taskQueue)
 	systemHandler := handlers.NewSystemHandler()
+	fileHandler := handlers.NewFileHandler(sambaService)
+	linkHandler := handlers.NewLinkHandler(linkService)
+	davHandler := handlers.NewDAVHandler(sambaService)
+	orphanRetentionHandler := handlers.NewOrphanRetentionHandler(orphanRetentionService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService, taskQueue)
+	adminHandler := handlers.NewAdminHandler(adminService)
+	sessionHandler := handlers.NewSessionHandler()
+	loginProtectionHandler := handlers.NewLoginProtectionHandler()
+	twoFactorHandler := handlers.NewTwoFactorHandler()
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
 
 	// Get embedded static file system
 	staticFS, err := getStaticFS()
@@ -65,9 +87,13 @@ func main() {
 
 	// Setup Gin router
 	router := gin.Default()
+	// Client IPs are used for login throttling and sessions, so forwarded headers are only trusted from known proxies
+	if err := router.SetTrustedProxies(config.AppConfig.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
 
 	// Setup routes (all handlers share the same queue to prevent concurrent smb.conf access)
-	routes.SetupRoutes(router, userHandler, shareHandler, userShareHandler, userProfileHandler, systemHandler)
+	routes.SetupRoutes(router, userHandler, shareHandler, userShareHandler, userProfileHandler, systemHandler, fileHandler, linkHandler, davHandler, orphanRetentionHandler, notificationHandler, passwordResetHandler, adminHandler, sessionHandler, loginProtectionHandler, twoFactorHandler, oidcHandler)
 
 	// Serve embedded frontend
 	router.NoRoute(func(c *gin.Context) {
@@ -87,6 +113,9 @@ func main() {
 		c.FileFromFS("/", http.FS(staticFS))
 	})
 
+	// Enforce the orphan retention policy in the background
+	orphanRetentionService.Start()
+
 	// Create HTTP server
 	addr := config.AppConfig.Server.Host + ":" + config.AppConfig.Server.Port
 	server := &http.Server{
@@ -108,6 +137,9 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
+	// Stop the retention scheduler before the queue it submits to
+	orphanRetentionService.Stop()
+
 	// Shutdown task queue
 	taskQueue.Shutdown()
 
@@ -0,0 +1,509 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	adminsFileName      = "admins.json"
+	sambaAdminsFileName = "samba_admins.json"
+)
+
+// adminsMu guards the admin accounts and Samba admin grant files
+var adminsMu sync.Mutex
+
+// dummyAdminHash is compared for unknown usernames so response times don't reveal which admins exist
+var (
+	dummyAdminHash     []byte
+	dummyAdminHashOnce sync.Once
+)
+
+// loadAdminsInternal reads all admin accounts (caller must hold adminsMu)
+func loadAdminsInternal() ([]types.AdminAccount, error) {
+	var admins []types.AdminAccount
+	if err := readJSONFile(dataFilePath(adminsFileName), &admins); err != nil {
+		return nil, err
+	}
+	for i := range admins {
+		if len(admins[i].Roles) == 0 {
+			admins[i].Roles = []string{types.AdminRoleFull}
+		}
+	}
+	return admins, nil
+}
+
+// countFullAdmins returns how many accounts have the full admin role
+func countFullAdmins(admins []types.AdminAccount) int {
+	count := 0
+	for _, admin := range admins {
+		if contains(admin.Roles, types.AdminRoleFull) {
+			count++
+		}
+	}
+	return count
+}
+
+// normalizeRoles removes duplicate roles, keeping their order
+func normalizeRoles(roles []string) []string {
+	normalized := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !contains(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+	return normalized
+}
+
+// saveAdminsInternal writes all admin accounts (caller must hold adminsMu)
+func saveAdminsInternal(admins []types.AdminAccount) error {
+	return writeJSONFile(dataFilePath(adminsFileName), admins)
+}
+
+// findAdmin returns the index of an admin account, or -1
+func findAdmin(admins []types.AdminAccount, username string) int {
+	for i := range admins {
+		if admins[i].Username == username {
+			return i
+		}
+	}
+	return -1
+}
+
+// hashAdminPassword hashes an admin password with bcrypt
+func hashAdminPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+	return string(hash), nil
+}
+
+// MigrateAdminAccounts moves the plaintext admin password from config.yaml to the hashed admin accounts
+// It runs on every start, so putting a plaintext password back into config.yaml resets that admin's password
+func MigrateAdminAccounts(configPath string) error {
+	username := config.AppConfig.Admin.Username
+	password := config.AppConfig.Admin.Password
+	if username == "" || password == "" {
+		return nil
+	}
+	if !isValidUsername(username) {
+		return fmt.Errorf("invalid admin username in config: %s", username)
+	}
+
+	hash, err := hashAdminPassword(password)
+	if err != nil {
+		return err
+	}
+
+	adminsMu.Lock()
+	admins, err := loadAdminsInternal()
+	if err == nil {
+		now := time.Now()
+		if i := findAdmin(admins, username); i >= 0 {
+			admins[i].PasswordHash = hash
+			admins[i].PasswordChangedAt = now
+			if !contains(admins[i].Roles, types.AdminRoleFull) {
+				admins[i].Roles = append(admins[i].Roles, types.AdminRoleFull)
+			}
+			log.Printf("Reset password of admin %s from config file", username)
+		} else {
+			admins = append(admins, types.AdminAccount{
+				Username:          username,
+				PasswordHash:      hash,
+				Roles:             []string{types.AdminRoleFull},
+				CreatedAt:         now,
+				PasswordChangedAt: now,
+			})
+			log.Printf("Migrated admin %s from config file", username)
+		}
+		err = saveAdminsInternal(admins)
+	}
+	adminsMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return config.ClearAdminPassword(configPath)
+}
+
+// VerifyAdminCredentials checks a username and password against the admin accounts
+func VerifyAdminCredentials(username string, password string) bool {
+	adminsMu.Lock()
+	admins, err := loadAdminsInternal()
+	adminsMu.Unlock()
+	if err != nil {
+		log.Printf("Failed to read admin accounts: %v", err)
+		return false
+	}
+
+	i := findAdmin(admins, username)
+	if i < 0 {
+		dummyAdminHashOnce.Do(func() {
+			dummyAdminHash, _ = bcrypt.GenerateFromPassword([]byte("not-an-admin"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyAdminHash, []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(admins[i].PasswordHash), []byte(password)) == nil
+}
+
+// IsAdminAccount reports whether a username belongs to a web administrator
+func IsAdminAccount(username string) bool {
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		log.Printf("Failed to read admin accounts: %v", err)
+		return false
+	}
+	return findAdmin(admins, username) >= 0
+}
+
+// GetAdminRoles returns the roles of a web administrator, or false if there is no such admin
+func GetAdminRoles(username string) ([]string, bool) {
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		log.Printf("Failed to read admin accounts: %v", err)
+		return nil, false
+	}
+	i := findAdmin(admins, username)
+	if i < 0 {
+		return nil, false
+	}
+	return admins[i].Roles, true
+}
+
+// loadSambaAdminsInternal reads the admin roles granted to Samba users (caller must hold adminsMu)
+func loadSambaAdminsInternal() (map[string]types.SambaAdminGrant, error) {
+	grants := make(map[string]types.SambaAdminGrant)
+	if err := readJSONFile(dataFilePath(sambaAdminsFileName), &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// updateSambaAdmins applies fn to the Samba admin grants and saves them
+func updateSambaAdmins(fn func(grants map[string]types.SambaAdminGrant) error) error {
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	grants, err := loadSambaAdminsInternal()
+	if err != nil {
+		return err
+	}
+	if err := fn(grants); err != nil {
+		return err
+	}
+	return writeJSONFile(dataFilePath(sambaAdminsFileName), grants)
+}
+
+// GetSambaAdminRoles returns the admin roles granted to a Samba user, or false if the user is no admin
+func GetSambaAdminRoles(username string) ([]string, bool) {
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	grants, err := loadSambaAdminsInternal()
+	if err != nil {
+		log.Printf("Failed to read samba admin grants: %v", err)
+		return nil, false
+	}
+	grant, ok := grants[username]
+	if !ok {
+		return nil, false
+	}
+	return grant.Roles, true
+}
+
+// deleteSambaAdmin revokes the admin roles of a deleted Samba user
+func deleteSambaAdmin(username string) error {
+	return updateSambaAdmins(func(grants map[string]types.SambaAdminGrant) error {
+		delete(grants, username)
+		return nil
+	})
+}
+
+// renameSambaAdmin moves the admin roles of a renamed Samba user
+func renameSambaAdmin(oldName string, newName string) error {
+	return updateSambaAdmins(func(grants map[string]types.SambaAdminGrant) error {
+		if grant, ok := grants[oldName]; ok {
+			grants[newName] = grant
+			delete(grants, oldName)
+		}
+		return nil
+	})
+}
+
+// oidcGrantedBy marks Samba admin grants managed by the OIDC identity provider
+const oidcGrantedBy = "oidc"
+
+// syncOIDCAdminRoles applies the admin roles mapped from identity provider claims to a Samba user
+// Grants made by an admin are only replaced when the claims map to roles, and only
+// grants made through OIDC are revoked when they no longer do. Reports whether the roles changed.
+func syncOIDCAdminRoles(username string, roles []string) (bool, error) {
+	roles = normalizeRoles(roles)
+	changed := false
+
+	err := updateSambaAdmins(func(grants map[string]types.SambaAdminGrant) error {
+		grant, ok := grants[username]
+		switch {
+		case len(roles) > 0:
+			if ok && grant.GrantedBy == oidcGrantedBy && sameRoles(grant.Roles, roles) {
+				return nil
+			}
+			grants[username] = types.SambaAdminGrant{
+				Roles:     roles,
+				GrantedBy: oidcGrantedBy,
+				GrantedAt: time.Now(),
+			}
+			changed = true
+		case ok && grant.GrantedBy == oidcGrantedBy:
+			delete(grants, username)
+			changed = true
+		}
+		return nil
+	})
+
+	return changed, err
+}
+
+// sameRoles reports whether two role lists contain the same roles
+func sameRoles(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, role := range a {
+		if !contains(b, role) {
+			return false
+		}
+	}
+	return true
+}
+
+// AdminService manages web administrator accounts
+type AdminService struct {
+	samba *SambaService
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(samba *SambaService) *AdminService {
+	return &AdminService{
+		samba: samba,
+	}
+}
+
+// ListAdmins lists all web administrators and promoted Samba users sorted by username
+func (s *AdminService) ListAdmins() ([]types.AdminResponse, error) {
+	adminsMu.Lock()
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		adminsMu.Unlock()
+		return nil, err
+	}
+	grants, err := loadSambaAdminsInternal()
+	adminsMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]types.AdminResponse, 0, len(admins)+len(grants))
+	for _, admin := range admins {
+		passwordChangedAt := admin.PasswordChangedAt
+		responses = append(responses, types.AdminResponse{
+			Username:          admin.Username,
+			Roles:             admin.Roles,
+			CreatedAt:         admin.CreatedAt,
+			PasswordChangedAt: &passwordChangedAt,
+		})
+	}
+	for username, grant := range grants {
+		responses = append(responses, types.AdminResponse{
+			Username:  username,
+			Roles:     grant.Roles,
+			SambaUser: true,
+			CreatedAt: grant.GrantedAt,
+			GrantedBy: grant.GrantedBy,
+		})
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		return responses[i].Username < responses[j].Username
+	})
+
+	return responses, nil
+}
+
+// CreateAdmin adds a web administrator
+// Admin names can't be Samba usernames, since the admin login is checked first
+func (s *AdminService) CreateAdmin(req *types.CreateAdminRequest) error {
+	if !isValidUsername(req.Username) {
+		return utils.NewValidationError("invalid username: must contain only letters, numbers, underscore, and dash")
+	}
+	if s.samba.UserExists(req.Username) {
+		return utils.NewValidationError(fmt.Sprintf("'%s' is already a Samba user", req.Username))
+	}
+
+	hash, err := hashAdminPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		return err
+	}
+	if findAdmin(admins, req.Username) >= 0 {
+		return utils.NewValidationError(fmt.Sprintf("admin '%s' already exists", req.Username))
+	}
+
+	now := time.Now()
+	admins = append(admins, types.AdminAccount{
+		Username:          req.Username,
+		PasswordHash:      hash,
+		Roles:             normalizeRoles(req.Roles),
+		CreatedAt:         now,
+		PasswordChangedAt: now,
+	})
+
+	return saveAdminsInternal(admins)
+}
+
+// DeleteAdmin removes a web administrator
+// Admins can't remove themselves, which also keeps at least one admin
+func (s *AdminService) DeleteAdmin(username string, currentAdmin string) error {
+	if username == currentAdmin {
+		return utils.NewForbiddenError("You can't delete your own admin account")
+	}
+
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		return err
+	}
+	i := findAdmin(admins, username)
+	if i < 0 {
+		return utils.NewNotFoundError("Admin not found")
+	}
+
+	admins = append(admins[:i], admins[i+1:]...)
+	if countFullAdmins(admins) == 0 {
+		return utils.NewForbiddenError("At least one full admin must remain")
+	}
+	if err := saveAdminsInternal(admins); err != nil {
+		return err
+	}
+
+	if err := deleteTwoFactor(username); err != nil {
+		log.Printf("Failed to delete two-factor authentication of admin %s: %v", username, err)
+	}
+	return nil
+}
+
+// UpdateAdminRoles replaces the roles of a web administrator
+// Admins can't remove their own full admin role, and at least one full admin must remain
+func (s *AdminService) UpdateAdminRoles(username string, currentAdmin string, roles []string) error {
+	roles = normalizeRoles(roles)
+	if username == currentAdmin && !contains(roles, types.AdminRoleFull) {
+		return utils.NewForbiddenError("You can't remove your own admin role")
+	}
+
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		return err
+	}
+	i := findAdmin(admins, username)
+	if i < 0 {
+		return utils.NewNotFoundError("Admin not found")
+	}
+
+	admins[i].Roles = roles
+	if countFullAdmins(admins) == 0 {
+		return utils.NewForbiddenError("At least one full admin must remain")
+	}
+	return saveAdminsInternal(admins)
+}
+
+// ChangeAdminPassword sets a new password for a web administrator
+// Changing your own password requires the current one
+func (s *AdminService) ChangeAdminPassword(username string, currentAdmin string, req *types.ChangeAdminPasswordRequest) error {
+	if username == currentAdmin && !VerifyAdminCredentials(username, req.CurrentPassword) {
+		return utils.NewUnauthorizedError("Invalid current password")
+	}
+
+	hash, err := hashAdminPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	adminsMu.Lock()
+	defer adminsMu.Unlock()
+
+	admins, err := loadAdminsInternal()
+	if err != nil {
+		return err
+	}
+	i := findAdmin(admins, username)
+	if i < 0 {
+		return utils.NewNotFoundError("Admin not found")
+	}
+
+	admins[i].PasswordHash = hash
+	admins[i].PasswordChangedAt = time.Now()
+	return saveAdminsInternal(admins)
+}
+
+// GrantSambaAdmin promotes an existing Samba user to admin, or replaces the roles of a promoted user
+// Admins can't remove their own full admin role
+func (s *AdminService) GrantSambaAdmin(username string, currentAdmin string, roles []string) error {
+	roles = normalizeRoles(roles)
+	if username == currentAdmin && !contains(roles, types.AdminRoleFull) {
+		return utils.NewForbiddenError("You can't remove your own admin role")
+	}
+	if !s.samba.UserExists(username) {
+		return utils.NewNotFoundError("User not found")
+	}
+
+	return updateSambaAdmins(func(grants map[string]types.SambaAdminGrant) error {
+		grants[username] = types.SambaAdminGrant{
+			Roles:     roles,
+			GrantedBy: currentAdmin,
+			GrantedAt: time.Now(),
+		}
+		return nil
+	})
+}
+
+// RevokeSambaAdmin turns a promoted Samba user back into a regular user
+func (s *AdminService) RevokeSambaAdmin(username string, currentAdmin string) error {
+	if username == currentAdmin {
+		return utils.NewForbiddenError("You can't revoke your own admin role")
+	}
+
+	return updateSambaAdmins(func(grants map[string]types.SambaAdminGrant) error {
+		if _, ok := grants[username]; !ok {
+			return utils.NewNotFoundError("User is not an admin")
+		}
+		delete(grants, username)
+		return nil
+	})
+}
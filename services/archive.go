@@ -0,0 +1,228 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/itsHenry35/SambaManager/config"
+)
+
+// ArchiveFormat represents a supported directory archive format
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// ParseArchiveFormat parses a format query parameter (defaults to zip)
+func ParseArchiveFormat(format string) (ArchiveFormat, error) {
+	switch format {
+	case "", "zip":
+		return ArchiveZip, nil
+	case "tar.gz", "tgz":
+		return ArchiveTarGz, nil
+	}
+	return "", fmt.Errorf("unsupported archive format: %s", format)
+}
+
+// ContentType returns the MIME type of the archive format
+func (f ArchiveFormat) ContentType() string {
+	if f == ArchiveTarGz {
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+// FileName returns the download file name for an archive of a directory
+func (f ArchiveFormat) FileName(dirName string) string {
+	return dirName + "." + string(f)
+}
+
+// ArchiveLimits bounds the size of generated archives
+type ArchiveLimits struct {
+	MaxTotalSize int64 // Maximum total size of archived files in bytes (0 = unlimited)
+	MaxEntries   int   // Maximum number of files and directories (0 = unlimited)
+}
+
+// DefaultArchiveLimits returns the archive limits from the configuration
+func DefaultArchiveLimits() ArchiveLimits {
+	return ArchiveLimits{
+		MaxTotalSize: config.AppConfig.Archive.MaxTotalSizeMB * 1024 * 1024,
+		MaxEntries:   config.AppConfig.Archive.MaxEntries,
+	}
+}
+
+// archiveEntryFunc is called for every entry included in an archive
+type archiveEntryFunc func(filePath string, name string, info fs.FileInfo) error
+
+// walkArchiveEntries walks a directory in archive order, enforcing limits and cancellation
+// Entry names are slash-separated and prefixed with the directory's base name
+// Symlinks and special files are skipped so the archive never reaches outside the directory
+func walkArchiveEntries(ctx context.Context, dirPath string, limits ArchiveLimits, fn archiveEntryFunc) error {
+	baseName := filepath.Base(dirPath)
+	var totalSize int64
+	var entries int
+
+	return filepath.WalkDir(dirPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip files/dirs that can't be accessed
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		entries++
+		if limits.MaxEntries > 0 && entries > limits.MaxEntries {
+			return fmt.Errorf("directory contains more than %d entries", limits.MaxEntries)
+		}
+		if !entry.IsDir() {
+			totalSize += info.Size()
+			if limits.MaxTotalSize > 0 && totalSize > limits.MaxTotalSize {
+				return fmt.Errorf("directory is larger than %d MB", limits.MaxTotalSize/1024/1024)
+			}
+		}
+
+		rel, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return fn(filePath, path.Join(baseName, filepath.ToSlash(rel)), info)
+	})
+}
+
+// CheckArchiveLimits scans a directory and fails if its archive would exceed the limits
+// Called before any response is written so the client still gets a proper error
+func CheckArchiveLimits(ctx context.Context, dirPath string, limits ArchiveLimits) error {
+	return walkArchiveEntries(ctx, dirPath, limits, func(string, string, fs.FileInfo) error {
+		return nil
+	})
+}
+
+// contextReader aborts reads once the context is canceled (e.g. the client disconnected)
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// copyFile copies a file's content into an archive entry, honouring cancellation
+// At most size bytes are copied so a file growing during the walk cannot break the entry
+func copyFile(ctx context.Context, w io.Writer, filePath string, size int64) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, io.LimitReader(&contextReader{ctx: ctx, reader: file}, size))
+	return err
+}
+
+// WriteArchive streams an archive of a directory to w without temporary files
+func WriteArchive(ctx context.Context, w io.Writer, dirPath string, format ArchiveFormat, limits ArchiveLimits) error {
+	var err error
+	if format == ArchiveTarGz {
+		err = writeTarGzArchive(ctx, w, dirPath, limits)
+	} else {
+		err = writeZipArchive(ctx, w, dirPath, limits)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s archive: %v", format, err)
+	}
+	return nil
+}
+
+// writeZipArchive streams a zip archive of a directory
+func writeZipArchive(ctx context.Context, w io.Writer, dirPath string, limits ArchiveLimits) error {
+	zipWriter := zip.NewWriter(w)
+
+	err := walkArchiveEntries(ctx, dirPath, limits, func(filePath string, name string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+
+		if info.IsDir() {
+			header.Name += "/"
+			_, err := zipWriter.CreateHeader(header)
+			return err
+		}
+
+		header.Method = zip.Deflate
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyFile(ctx, writer, filePath, info.Size())
+	})
+	if err != nil {
+		return err
+	}
+
+	return zipWriter.Close()
+}
+
+// writeTarGzArchive streams a gzip-compressed tar archive of a directory
+func writeTarGzArchive(ctx context.Context, w io.Writer, dirPath string, limits ArchiveLimits) error {
+	gzipWriter := gzip.NewWriter(w)
+	if err := writeTarArchive(ctx, gzipWriter, dirPath, limits); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// writeTarArchive streams an uncompressed tar archive of a directory
+func writeTarArchive(ctx context.Context, w io.Writer, dirPath string, limits ArchiveLimits) error {
+	tarWriter := tar.NewWriter(w)
+
+	err := walkArchiveEntries(ctx, dirPath, limits, func(filePath string, name string, info fs.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		// Files are owned by root on the server, ownership is meaningless to the recipient
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+		if info.IsDir() {
+			header.Name += "/"
+			return tarWriter.WriteHeader(header)
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		return copyFile(ctx, tarWriter, filePath, info.Size())
+	})
+	if err != nil {
+		return err
+	}
+
+	return tarWriter.Close()
+}
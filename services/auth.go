@@ -0,0 +1,19 @@
+package services
+
+// CheckSambaPassword checks a password, reporting separately whether Samba requires it to be changed
+// Shared by the web login and WebDAV so both accept exactly the same credentials
+// Samba only reports an expired password after the password itself was accepted
+func CheckSambaPassword(username string, password string) (valid bool, expired bool) {
+	// SECURITY: validate username format to prevent command injection
+	if !isValidUsername(username) {
+		return false, false
+	}
+
+	// First check if user exists in Samba and is not disabled
+	if _, active := GetUserStatus(username); !active {
+		return false, false
+	}
+
+	// Verify the password with the configured verifiers (see credentials.go)
+	return verifySambaPassword(username, password)
+}
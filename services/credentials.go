@@ -0,0 +1,134 @@
+package services
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"golang.org/x/crypto/md4"
+)
+
+// CredentialVerifier checks the password of an existing, enabled Samba user
+// An error means the verifier could not decide (e.g. a missing tool), and the next verifier is tried
+type CredentialVerifier interface {
+	Verify(username string, password string) (valid bool, expired bool, err error)
+}
+
+// Password verifier names for the samba.password_verifier setting
+const (
+	VerifierAuto      = "auto" // NT hash, falling back to smbclient
+	VerifierNTHash    = "nthash"
+	VerifierSmbclient = "smbclient"
+)
+
+// credentialVerifiers are the available verifiers by name
+var credentialVerifiers = map[string]CredentialVerifier{
+	VerifierNTHash:    ntHashVerifier{},
+	VerifierSmbclient: smbclientVerifier{},
+}
+
+// credentialVerifierChain returns the verifiers to try in order, according to the configuration
+func credentialVerifierChain() []CredentialVerifier {
+	if verifier, ok := credentialVerifiers[config.AppConfig.Samba.PasswordVerifier]; ok {
+		return []CredentialVerifier{verifier}
+	}
+	return []CredentialVerifier{credentialVerifiers[VerifierNTHash], credentialVerifiers[VerifierSmbclient]}
+}
+
+// verifySambaPassword runs the configured verifiers until one of them decides
+func verifySambaPassword(username string, password string) (bool, bool) {
+	for _, verifier := range credentialVerifierChain() {
+		valid, expired, err := verifier.Verify(username, password)
+		if err == nil {
+			return valid, expired
+		}
+		log.Printf("Password verifier %T failed for %s, trying the next one: %v", verifier, username, err)
+	}
+	return false, false
+}
+
+// ntHashVerifier compares the NT hash of the password with the one stored in the passdb
+// The password never leaves this process and smbd does not have to be running
+// Samba's own bad password count is not updated, the login throttling applies instead
+type ntHashVerifier struct{}
+
+// Verify implements CredentialVerifier
+func (ntHashVerifier) Verify(username string, password string) (bool, bool, error) {
+	// Export the account in smbpasswd format: name:uid:LM:NT:[flags]:LCT-...:
+	cmd := exec.Command("pdbedit", "-L", "-w", "-u", username)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read samba account: %v, output: %s", err, output)
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(output)), ":")
+	if len(fields) < 6 || fields[0] != username {
+		return false, false, fmt.Errorf("unexpected pdbedit output for user %s", username)
+	}
+
+	// Accounts without a stored hash ("NO PASSWORD...") or locked out by Samba never match
+	storedHash, err := hex.DecodeString(fields[3])
+	if err != nil || len(storedHash) != md4.Size || strings.Contains(fields[4], "L") {
+		return false, false, nil
+	}
+	if subtle.ConstantTimeCompare(ntHash(password), storedHash) != 1 {
+		return false, false, nil
+	}
+
+	// Like Samba, only report an expired password once the password itself was accepted
+	account, err := getSambaAccount(username)
+	if err != nil {
+		return false, false, err
+	}
+	expired := account.PasswordMustChange != nil && !account.PasswordMustChange.After(time.Now())
+	return true, expired, nil
+}
+
+// ntHash returns the NT hash of a password: MD4 of its UTF-16LE encoding
+func ntHash(password string) []byte {
+	units := utf16.Encode([]rune(password))
+	encoded := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(encoded[2*i:], unit)
+	}
+
+	hash := md4.New()
+	hash.Write(encoded)
+	return hash.Sum(nil)
+}
+
+// smbclientVerifier logs on to the local smbd with the credentials
+// Slower and requires smbd, but also applies Samba's bad password count and lockout
+type smbclientVerifier struct{}
+
+// Verify implements CredentialVerifier
+func (smbclientVerifier) Verify(username string, password string) (bool, bool, error) {
+	if _, err := exec.LookPath("smbclient"); err != nil {
+		return false, false, fmt.Errorf("smbclient not available: %v", err)
+	}
+
+	// The password is passed in the environment, which unlike arguments is not visible to other users
+	cmd := exec.Command("smbclient", "-L", "localhost", "-U", username)
+	cmd.Env = append(os.Environ(), "PASSWD="+password)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return true, false, nil
+	}
+
+	if strings.Contains(string(output), "NT_STATUS_PASSWORD_MUST_CHANGE") ||
+		strings.Contains(string(output), "NT_STATUS_PASSWORD_EXPIRED") {
+		return true, true, nil
+	}
+	if strings.Contains(string(output), "NT_STATUS_CONNECTION_REFUSED") {
+		return false, false, fmt.Errorf("smbd is not reachable")
+	}
+	return false, false, nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+const (
+	davHomeDir   = "home"   // Virtual folder mapped to the user's home directory
+	davSharesDir = "shares" // Virtual folder containing one folder per granted share
+)
+
+// DAVFileSystem exposes a user's home and granted shares as a virtual WebDAV tree:
+//
+//	/home/...            the user's home directory
+//	/shares/<shareId>/... shares the user is allowed to access
+//
+// Paths are confined with FileService.ResolvePath and read-only shares refuse writes
+type DAVFileSystem struct {
+	username    string
+	samba       *SambaService
+	fileService *FileService
+}
+
+// NewDAVFileSystem creates the virtual WebDAV tree for a user
+func NewDAVFileSystem(samba *SambaService, username string) *DAVFileSystem {
+	return &DAVFileSystem{
+		username:    username,
+		samba:       samba,
+		fileService: NewFileService(),
+	}
+}
+
+// davTarget is the result of mapping a WebDAV path onto the virtual tree
+type davTarget struct {
+	virtual  []string    // Children names if the path is a virtual directory
+	root     *BrowseRoot // Root of the real tree the path belongs to
+	relPath  string      // Path relative to root
+	rootName string      // Name of the root folder in the virtual tree
+}
+
+// isRoot reports whether the target is the top of a home or share tree
+func (t *davTarget) isRoot() bool {
+	return t.virtual == nil && t.relPath == ""
+}
+
+// resolve maps a slash-separated WebDAV path onto the virtual tree
+func (fs *DAVFileSystem) resolve(name string) (*davTarget, error) {
+	parts := strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/")
+	if len(parts) == 1 && parts[0] == "" {
+		return &davTarget{virtual: []string{davHomeDir, davSharesDir}}, nil
+	}
+
+	switch parts[0] {
+	case davHomeDir:
+		root, err := fs.samba.GetHomeRoot(fs.username)
+		if err != nil {
+			return nil, os.ErrNotExist
+		}
+		return &davTarget{root: root, relPath: strings.Join(parts[1:], "/"), rootName: davHomeDir}, nil
+
+	case davSharesDir:
+		if len(parts) == 1 {
+			shares, err := fs.samba.ListShares()
+			if err != nil {
+				return nil, err
+			}
+			names := []string{}
+			for _, share := range shares {
+				if share.Owner == fs.username || contains(share.SharedWith, fs.username) {
+					names = append(names, share.ID)
+				}
+			}
+			return &davTarget{virtual: names}, nil
+		}
+
+		root, err := fs.samba.GetShareRoot(parts[1], fs.username)
+		if err != nil {
+			return nil, os.ErrNotExist
+		}
+		return &davTarget{root: root, relPath: strings.Join(parts[2:], "/"), rootName: parts[1]}, nil
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// realPath resolves a target to an absolute confined path
+func (fs *DAVFileSystem) realPath(target *davTarget) (string, error) {
+	fullPath, err := fs.fileService.ResolvePath(target.root, target.relPath)
+	if err != nil {
+		return "", os.ErrPermission
+	}
+	return fullPath, nil
+}
+
+// resolveWritable resolves a path for modification, refusing virtual and read-only locations
+func (fs *DAVFileSystem) resolveWritable(name string) (*davTarget, string, error) {
+	target, err := fs.resolve(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if target.virtual != nil || target.root.ReadOnly {
+		return nil, "", os.ErrPermission
+	}
+
+	// Resolve the parent but not the final element, so symlinks are modified rather than their targets
+	parentPath, err := fs.fileService.ResolvePath(target.root, path.Dir(target.relPath))
+	if err != nil {
+		return nil, "", os.ErrPermission
+	}
+
+	return target, parentPath + "/" + path.Base(target.relPath), nil
+}
+
+// Mkdir creates a directory
+func (fs *DAVFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	target, fullPath, err := fs.resolveWritable(name)
+	if err != nil {
+		return err
+	}
+	if target.isRoot() {
+		return os.ErrExist
+	}
+	return os.Mkdir(fullPath, 0770)
+}
+
+// OpenFile opens a file or directory, refusing write flags on read-only locations
+func (fs *DAVFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	target, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.virtual != nil {
+		if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+			return nil, os.ErrPermission
+		}
+		return &davVirtualDir{name: path.Base("/" + name), children: target.virtual}, nil
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		if target.root.ReadOnly {
+			return nil, os.ErrPermission
+		}
+		if target.isRoot() {
+			return nil, os.ErrPermission
+		}
+	}
+
+	fullPath, err := fs.realPath(target)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(fullPath, flag, 0660)
+	if err != nil {
+		return nil, err
+	}
+	if target.isRoot() {
+		return &davRootFile{File: file, name: target.rootName}, nil
+	}
+	return file, nil
+}
+
+// RemoveAll deletes a file or directory tree
+func (fs *DAVFileSystem) RemoveAll(ctx context.Context, name string) error {
+	target, fullPath, err := fs.resolveWritable(name)
+	if err != nil {
+		return err
+	}
+	if target.isRoot() {
+		return os.ErrPermission
+	}
+	return os.RemoveAll(fullPath)
+}
+
+// Rename moves a file or directory within the same home or share
+func (fs *DAVFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	oldTarget, oldPath, err := fs.resolveWritable(oldName)
+	if err != nil {
+		return err
+	}
+	newTarget, newPath, err := fs.resolveWritable(newName)
+	if err != nil {
+		return err
+	}
+	if oldTarget.isRoot() || newTarget.isRoot() {
+		return os.ErrPermission
+	}
+	if oldTarget.root.Path != newTarget.root.Path {
+		// Moving between trees would bypass per-share permissions, clients fall back to copy+delete
+		return os.ErrPermission
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+// Stat returns file information
+func (fs *DAVFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	target, err := fs.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if target.virtual != nil {
+		return &davDirInfo{name: path.Base("/" + name)}, nil
+	}
+
+	fullPath, err := fs.realPath(target)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if target.isRoot() {
+		return &davRootInfo{FileInfo: info, name: target.rootName}, nil
+	}
+	return info, nil
+}
+
+// davDirInfo describes a virtual directory
+type davDirInfo struct {
+	name string
+}
+
+func (i *davDirInfo) Name() string       { return i.name }
+func (i *davDirInfo) Size() int64        { return 0 }
+func (i *davDirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (i *davDirInfo) ModTime() time.Time { return time.Time{} }
+func (i *davDirInfo) IsDir() bool        { return true }
+func (i *davDirInfo) Sys() interface{}   { return nil }
+
+// davRootInfo renames a home or share root to its virtual folder name
+type davRootInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i *davRootInfo) Name() string { return i.name }
+
+// davRootFile reports its virtual folder name from Stat
+type davRootFile struct {
+	*os.File
+	name string
+}
+
+func (f *davRootFile) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &davRootInfo{FileInfo: info, name: f.name}, nil
+}
+
+// davVirtualDir is a read-only directory listing virtual children
+type davVirtualDir struct {
+	name     string
+	children []string
+	offset   int
+}
+
+func (d *davVirtualDir) Close() error { return nil }
+
+func (d *davVirtualDir) Read(p []byte) (int, error) { return 0, os.ErrInvalid }
+
+func (d *davVirtualDir) Seek(offset int64, whence int) (int64, error) { return 0, os.ErrInvalid }
+
+func (d *davVirtualDir) Write(p []byte) (int, error) { return 0, os.ErrPermission }
+
+func (d *davVirtualDir) Stat() (os.FileInfo, error) { return &davDirInfo{name: d.name}, nil }
+
+func (d *davVirtualDir) Readdir(count int) ([]os.FileInfo, error) {
+	remaining := d.children[d.offset:]
+	if count > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > 0 && count < len(remaining) {
+		remaining = remaining[:count]
+	}
+	d.offset += len(remaining)
+
+	infos := make([]os.FileInfo, 0, len(remaining))
+	for _, child := range remaining {
+		infos = append(infos, &davDirInfo{name: child})
+	}
+	return infos, nil
+}
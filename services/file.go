@@ -0,0 +1,378 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// FileService handles file browsing inside user homes and shares
+type FileService struct{}
+
+// NewFileService creates a new file service
+func NewFileService() *FileService {
+	return &FileService{}
+}
+
+// BrowseRoot describes a directory tree a user is allowed to browse
+type BrowseRoot struct {
+	Path     string // Absolute path of the root directory
+	ReadOnly bool   // Whether write operations are refused
+}
+
+// GetHomeRoot returns the browsable root for a user's own home directory
+func (s *SambaService) GetHomeRoot(username string) (*BrowseRoot, error) {
+	if !isValidUsername(username) {
+		return nil, utils.NewValidationError("invalid username")
+	}
+
+	homeDir := filepath.Join(config.AppConfig.HomeDir, username)
+	if _, err := os.Stat(homeDir); os.IsNotExist(err) {
+		return nil, utils.NewNotFoundError("Home directory does not exist")
+	}
+
+	return &BrowseRoot{Path: homeDir}, nil
+}
+
+// GetShareRoot returns the browsable root of a share if the user is allowed to access it
+// Access is granted to users in the share's valid users list and to its owner
+func (s *SambaService) GetShareRoot(shareID string, username string) (*BrowseRoot, error) {
+	shares, err := s.ListShares()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, share := range shares {
+		if share.ID != shareID {
+			continue
+		}
+		if share.Owner != username && !contains(share.SharedWith, username) {
+			return nil, utils.NewForbiddenError("You do not have access to this share")
+		}
+		if share.Path == "" {
+			return nil, fmt.Errorf("share '%s' has no path configured", shareID)
+		}
+		return &BrowseRoot{Path: share.Path, ReadOnly: share.ReadOnly}, nil
+	}
+
+	return nil, utils.NewNotFoundError("Share not found")
+}
+
+// ListSharesForUser lists shares that have been granted to a user by other users
+func (s *SambaService) ListSharesForUser(username string) ([]types.SharedWithMeResponse, error) {
+	shares, err := s.ListShares()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []types.SharedWithMeResponse{}
+	for _, share := range shares {
+		if share.Owner != username && contains(share.SharedWith, username) {
+			result = append(result, types.SharedWithMeResponse{
+				ID:       share.ID,
+				Owner:    share.Owner,
+				ReadOnly: share.ReadOnly,
+				Comment:  share.Comment,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// ResolvePath maps a client supplied relative path onto the root and returns the absolute path
+// Symlinks are resolved and must stay inside the root, matching smbd's default "wide links = no"
+func (s *FileService) ResolvePath(root *BrowseRoot, relPath string) (string, error) {
+	cleaned, err := cleanSubPath(relPath)
+	if err != nil {
+		return "", err
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root directory: %v", err)
+	}
+
+	fullPath := filepath.Join(realRoot, cleaned)
+	realPath, err := filepath.EvalSymlinks(fullPath)
+	if os.IsNotExist(err) {
+		// Target does not exist yet (e.g. upload or mkdir), confine its parent instead
+		realParent, parentErr := filepath.EvalSymlinks(filepath.Dir(fullPath))
+		if parentErr != nil {
+			return "", utils.NewNotFoundError("Parent directory does not exist")
+		}
+		realPath = filepath.Join(realParent, filepath.Base(fullPath))
+	} else if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %v", err)
+	}
+
+	if realPath != realRoot && !strings.HasPrefix(realPath, realRoot+string(os.PathSeparator)) {
+		return "", utils.NewForbiddenError("Path is outside of the allowed directory")
+	}
+
+	return realPath, nil
+}
+
+// resolveEntryPath resolves the parent directory of a path but keeps the final element as is
+// Used for delete and rename so that a symlink itself is affected rather than its target
+func (s *FileService) resolveEntryPath(root *BrowseRoot, relPath string) (string, error) {
+	cleaned, err := cleanSubPath(relPath)
+	if err != nil {
+		return "", err
+	}
+	if cleaned == "" {
+		return s.ResolvePath(root, "")
+	}
+
+	parentPath, err := s.ResolvePath(root, filepath.Dir(cleaned))
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(parentPath, filepath.Base(cleaned)), nil
+}
+
+// relativePath returns the path of an absolute location relative to the root, using forward slashes
+func relativePath(root string, fullPath string) string {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	rel, err := filepath.Rel(realRoot, fullPath)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ListDirectory lists the entries of a directory inside the root
+func (s *FileService) ListDirectory(root *BrowseRoot, relPath string) (*types.DirectoryListingResponse, error) {
+	dirPath, err := s.ResolvePath(root, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(dirPath)
+	if os.IsNotExist(err) {
+		return nil, utils.NewNotFoundError("Directory not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, utils.NewValidationError("path is not a directory")
+	}
+
+	dirEntries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	entries := []types.FileEntry{}
+	for _, dirEntry := range dirEntries {
+		entryPath := filepath.Join(dirPath, dirEntry.Name())
+
+		// Follow symlinks for metadata, skipping those that escape the root
+		entryInfo, err := os.Stat(entryPath)
+		if err != nil {
+			continue
+		}
+		if dirEntry.Type()&os.ModeSymlink != 0 {
+			if _, err := s.ResolvePath(root, relativePath(root.Path, entryPath)); err != nil {
+				continue
+			}
+		}
+
+		entry := types.FileEntry{
+			Name:    dirEntry.Name(),
+			Path:    relativePath(root.Path, entryPath),
+			IsDir:   entryInfo.IsDir(),
+			ModTime: entryInfo.ModTime(),
+		}
+		if !entryInfo.IsDir() {
+			entry.Size = entryInfo.Size()
+		}
+		entries = append(entries, entry)
+	}
+
+	// Directories first, then by name
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+
+	return &types.DirectoryListingResponse{
+		Path:     relativePath(root.Path, dirPath),
+		ReadOnly: root.ReadOnly,
+		Entries:  entries,
+	}, nil
+}
+
+// StatFile resolves a regular file inside the root for download
+func (s *FileService) StatFile(root *BrowseRoot, relPath string) (string, os.FileInfo, error) {
+	filePath, err := s.ResolvePath(root, relPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return "", nil, utils.NewNotFoundError("File not found")
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	if info.IsDir() {
+		return "", nil, utils.NewValidationError("path is a directory")
+	}
+
+	return filePath, info, nil
+}
+
+// StatDirectory resolves a directory inside the root for archive download
+func (s *FileService) StatDirectory(root *BrowseRoot, relPath string) (string, error) {
+	dirPath, err := s.ResolvePath(root, relPath)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(dirPath)
+	if os.IsNotExist(err) {
+		return "", utils.NewNotFoundError("Directory not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat directory: %v", err)
+	}
+	if !info.IsDir() {
+		return "", utils.NewValidationError("path is not a directory")
+	}
+
+	return dirPath, nil
+}
+
+// SaveFile writes uploaded content to a file inside the root, replacing any existing file
+func (s *FileService) SaveFile(root *BrowseRoot, relPath string, content io.Reader) error {
+	if root.ReadOnly {
+		return utils.NewForbiddenError("This share is read-only")
+	}
+
+	filePath, err := s.ResolvePath(root, relPath)
+	if err != nil {
+		return err
+	}
+	if relativePath(root.Path, filePath) == "" {
+		return utils.NewValidationError("invalid file path")
+	}
+
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		return utils.NewValidationError("a directory with the same name already exists")
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0660)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %v", err)
+	}
+
+	return nil
+}
+
+// CreateDirectory creates a directory inside the root
+func (s *FileService) CreateDirectory(root *BrowseRoot, relPath string) error {
+	if root.ReadOnly {
+		return utils.NewForbiddenError("This share is read-only")
+	}
+
+	dirPath, err := s.ResolvePath(root, relPath)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(dirPath); err == nil && !info.IsDir() {
+		return utils.NewValidationError("a file with the same name already exists")
+	}
+
+	if err := os.MkdirAll(dirPath, 0770); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	return nil
+}
+
+// DeletePath deletes a file or directory inside the root
+func (s *FileService) DeletePath(root *BrowseRoot, relPath string) error {
+	if root.ReadOnly {
+		return utils.NewForbiddenError("This share is read-only")
+	}
+
+	targetPath, err := s.resolveEntryPath(root, relPath)
+	if err != nil {
+		return err
+	}
+	if relativePath(root.Path, targetPath) == "" {
+		return utils.NewForbiddenError("Cannot delete the root directory")
+	}
+
+	if _, err := os.Lstat(targetPath); os.IsNotExist(err) {
+		return utils.NewNotFoundError("File not found")
+	}
+
+	if err := os.RemoveAll(targetPath); err != nil {
+		return fmt.Errorf("failed to delete: %v", err)
+	}
+
+	return nil
+}
+
+// RenamePath renames or moves a file or directory within the root
+func (s *FileService) RenamePath(root *BrowseRoot, from string, to string) error {
+	if root.ReadOnly {
+		return utils.NewForbiddenError("This share is read-only")
+	}
+
+	fromPath, err := s.resolveEntryPath(root, from)
+	if err != nil {
+		return err
+	}
+	toPath, err := s.resolveEntryPath(root, to)
+	if err != nil {
+		return err
+	}
+	if relativePath(root.Path, fromPath) == "" || relativePath(root.Path, toPath) == "" {
+		return utils.NewForbiddenError("Cannot rename the root directory")
+	}
+
+	if _, err := os.Lstat(fromPath); os.IsNotExist(err) {
+		return utils.NewNotFoundError("File not found")
+	}
+	if _, err := os.Lstat(toPath); err == nil {
+		return utils.NewValidationError("destination already exists")
+	}
+
+	if info, err := os.Lstat(fromPath); err == nil && info.IsDir() && strings.HasPrefix(toPath, fromPath+string(os.PathSeparator)) {
+		return utils.NewValidationError("cannot move a directory into itself")
+	}
+
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return fmt.Errorf("failed to rename: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	linksFileName      = "links.json"
+	linkPolicyFileName = "link_policy.json"
+)
+
+// LinkService manages public download links to files and folders in user homes
+type LinkService struct {
+	mu          sync.Mutex
+	samba       *SambaService
+	fileService *FileService
+}
+
+// NewLinkService creates a new link service
+func NewLinkService(samba *SambaService) *LinkService {
+	return &LinkService{
+		samba:       samba,
+		fileService: NewFileService(),
+	}
+}
+
+// loadLinks reads all stored links, dropping expired ones (must hold lock)
+func (s *LinkService) loadLinks() ([]types.DownloadLink, error) {
+	var links []types.DownloadLink
+	if err := readJSONFile(dataFilePath(linksFileName), &links); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]types.DownloadLink, 0, len(links))
+	for _, link := range links {
+		if link.ExpiresAt == nil || link.ExpiresAt.After(now) {
+			active = append(active, link)
+		}
+	}
+
+	return active, nil
+}
+
+// saveLinks writes all links (must hold lock)
+func (s *LinkService) saveLinks(links []types.DownloadLink) error {
+	return writeJSONFile(dataFilePath(linksFileName), links)
+}
+
+// GetPolicy returns the current download link policy
+func (s *LinkService) GetPolicy() (*types.DownloadLinkPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getPolicyInternal()
+}
+
+// getPolicyInternal reads the policy without acquiring lock (links are enabled by default)
+func (s *LinkService) getPolicyInternal() (*types.DownloadLinkPolicy, error) {
+	policy := &types.DownloadLinkPolicy{Enabled: true}
+	if err := readJSONFile(dataFilePath(linkPolicyFileName), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces the download link policy
+func (s *LinkService) UpdatePolicy(policy *types.DownloadLinkPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeJSONFile(dataFilePath(linkPolicyFileName), policy)
+}
+
+// generateLinkID generates a random link identifier
+func generateLinkID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate link ID: %v", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// signLink computes the signature binding a link ID to its expiry
+func signLink(link *types.DownloadLink) string {
+	var expiry int64
+	if link.ExpiresAt != nil {
+		expiry = link.ExpiresAt.Unix()
+	}
+	mac := hmac.New(sha256.New, config.GetJWTSecret())
+	mac.Write([]byte(fmt.Sprintf("link:%s:%d", link.ID, expiry)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// linkToken builds the public token for a link ("<id>.<signature>")
+func linkToken(link *types.DownloadLink) string {
+	return link.ID + "." + signLink(link)
+}
+
+// toLinkResponse converts a stored link to its API representation
+func toLinkResponse(link *types.DownloadLink) types.DownloadLinkResponse {
+	token := linkToken(link)
+	return types.DownloadLinkResponse{
+		ID:          link.ID,
+		Owner:       link.Owner,
+		Path:        link.Path,
+		IsDir:       link.IsDir,
+		HasPassword: link.PasswordHash != "",
+		CreatedAt:   link.CreatedAt,
+		ExpiresAt:   link.ExpiresAt,
+		Downloads:   link.Downloads,
+		Token:       token,
+		URL:         "/api/public/links/" + token + "/download",
+	}
+}
+
+// CreateLink creates a public download link for a file or folder in the owner's home
+func (s *LinkService) CreateLink(owner string, req *types.CreateDownloadLinkRequest) (*types.DownloadLinkResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy, err := s.getPolicyInternal()
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, utils.NewForbiddenError("Public download links are disabled by the administrator")
+	}
+
+	// Apply the lifetime cap: links without expiry get the maximum lifetime
+	expiresInHours := req.ExpiresInHours
+	if policy.MaxLifetimeHours > 0 {
+		if expiresInHours == 0 {
+			expiresInHours = policy.MaxLifetimeHours
+		} else if expiresInHours > policy.MaxLifetimeHours {
+			return nil, fmt.Errorf("link lifetime cannot exceed %d hours", policy.MaxLifetimeHours)
+		}
+	}
+
+	root, err := s.samba.GetHomeRoot(owner)
+	if err != nil {
+		return nil, err
+	}
+	targetPath, err := s.fileService.ResolvePath(root, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(targetPath)
+	if os.IsNotExist(err) {
+		return nil, utils.NewNotFoundError("File not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+
+	relPath := relativePath(root.Path, targetPath)
+	if relPath == "" {
+		return nil, fmt.Errorf("cannot share the entire home directory")
+	}
+
+	id, err := generateLinkID()
+	if err != nil {
+		return nil, err
+	}
+
+	link := types.DownloadLink{
+		ID:        id,
+		Owner:     owner,
+		Path:      relPath,
+		IsDir:     info.IsDir(),
+		CreatedAt: time.Now(),
+	}
+	if expiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(expiresInHours) * time.Hour)
+		link.ExpiresAt = &expiresAt
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash link password: %v", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	links, err := s.loadLinks()
+	if err != nil {
+		return nil, err
+	}
+	links = append(links, link)
+	if err := s.saveLinks(links); err != nil {
+		return nil, err
+	}
+
+	response := toLinkResponse(&link)
+	return &response, nil
+}
+
+// ListLinks lists links created by a user, or all links if owner is empty
+func (s *LinkService) ListLinks(owner string) ([]types.DownloadLinkResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links, err := s.loadLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []types.DownloadLinkResponse{}
+	for i := range links {
+		if owner == "" || links[i].Owner == owner {
+			result = append(result, toLinkResponse(&links[i]))
+		}
+	}
+
+	// Newest first
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// RevokeLink deletes a link; a non-empty owner restricts revocation to that user's links
+func (s *LinkService) RevokeLink(linkID string, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links, err := s.loadLinks()
+	if err != nil {
+		return err
+	}
+
+	for i, link := range links {
+		if link.ID != linkID {
+			continue
+		}
+		if owner != "" && link.Owner != owner {
+			return utils.NewForbiddenError("You can only revoke your own links")
+		}
+		links = append(links[:i], links[i+1:]...)
+		return s.saveLinks(links)
+	}
+
+	return utils.NewNotFoundError("Link not found")
+}
+
+// RevokeUserLinks deletes all links created by a user (used when the user is deleted)
+func (s *LinkService) RevokeUserLinks(owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links, err := s.loadLinks()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]types.DownloadLink, 0, len(links))
+	for _, link := range links {
+		if link.Owner != owner {
+			remaining = append(remaining, link)
+		}
+	}
+
+	return s.saveLinks(remaining)
+}
+
+// RenameUserLinks moves all links created by a user to their new username
+func (s *LinkService) RenameUserLinks(oldOwner string, newOwner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links, err := s.loadLinks()
+	if err != nil {
+		return err
+	}
+
+	for i := range links {
+		if links[i].Owner == oldOwner {
+			links[i].Owner = newOwner
+		}
+	}
+
+	return s.saveLinks(links)
+}
+
+// findPublicLink validates a public token and returns the matching link (must hold lock)
+// Unknown, tampered and expired tokens are all reported as not found, as are links older than the current lifetime cap
+func (s *LinkService) findPublicLink(token string) ([]types.DownloadLink, int, error) {
+	policy, err := s.getPolicyInternal()
+	if err != nil {
+		return nil, -1, err
+	}
+	if !policy.Enabled {
+		return nil, -1, utils.NewForbiddenError("Public download links are disabled")
+	}
+
+	id, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, -1, utils.NewNotFoundError("Link not found or expired")
+	}
+
+	links, err := s.loadLinks()
+	if err != nil {
+		return nil, -1, err
+	}
+
+	for i := range links {
+		if links[i].ID != id {
+			continue
+		}
+		if !hmac.Equal([]byte(signature), []byte(signLink(&links[i]))) {
+			break
+		}
+		// A lowered cap also applies to links created before
+		if policy.MaxLifetimeHours > 0 && time.Since(links[i].CreatedAt) > time.Duration(policy.MaxLifetimeHours)*time.Hour {
+			break
+		}
+		return links, i, nil
+	}
+
+	return nil, -1, utils.NewNotFoundError("Link not found or expired")
+}
+
+// resolveLinkTarget returns the absolute path a link points to, re-checking confinement
+func (s *LinkService) resolveLinkTarget(link *types.DownloadLink) (string, os.FileInfo, error) {
+	// Links of deleted or disabled users stop working
+	if _, active := GetUserStatus(link.Owner); !active {
+		return "", nil, utils.NewNotFoundError("Link not found or expired")
+	}
+
+	root, err := s.samba.GetHomeRoot(link.Owner)
+	if err != nil {
+		return "", nil, err
+	}
+	targetPath, err := s.fileService.ResolvePath(root, link.Path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		return "", nil, utils.NewNotFoundError("The linked file no longer exists")
+	}
+
+	return targetPath, info, nil
+}
+
+// GetPublicLinkInfo returns what an anonymous visitor may see about a link
+func (s *LinkService) GetPublicLinkInfo(token string) (*types.PublicLinkInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links, index, err := s.findPublicLink(token)
+	if err != nil {
+		return nil, err
+	}
+	link := links[index]
+
+	_, info, err := s.resolveLinkTarget(&link)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.PublicLinkInfo{
+		Name:             filepath.Base(link.Path),
+		IsDir:            info.IsDir(),
+		PasswordRequired: link.PasswordHash != "",
+		ExpiresAt:        link.ExpiresAt,
+	}
+	if !info.IsDir() {
+		result.Size = info.Size()
+	}
+
+	return result, nil
+}
+
+// OpenPublicLink validates the token and password and returns the target to stream
+// The download counter is incremented on success. Wrong passwords are throttled per link and client IP address
+func (s *LinkService) OpenPublicLink(token string, password string, ip string) (string, os.FileInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links, index, err := s.findPublicLink(token)
+	if err != nil {
+		return "", nil, err
+	}
+	link := &links[index]
+
+	if link.PasswordHash != "" {
+		if password == "" {
+			return "", nil, utils.NewUnauthorizedError("Invalid link password")
+		}
+		if wait, allowed := CheckLinkPasswordAllowed(link.ID, ip); !allowed {
+			return "", nil, utils.NewTooManyRequestsError("Too many wrong passwords, try again later", wait)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			RecordLinkPasswordFailure(link.ID, ip)
+			return "", nil, utils.NewUnauthorizedError("Invalid link password")
+		}
+		RecordLinkPasswordSuccess(link.ID, ip)
+	}
+
+	targetPath, info, err := s.resolveLinkTarget(link)
+	if err != nil {
+		return "", nil, err
+	}
+
+	link.Downloads++
+	if err := s.saveLinks(links); err != nil {
+		return "", nil, err
+	}
+
+	return targetPath, info, nil
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	loginThrottlesFileName = "login_throttles.json"
+	loginFailuresFileName  = "login_failures.json"
+
+	// maxLoginFailureRecords limits the size of the failed login audit log
+	maxLoginFailureRecords = 5000
+
+	// Reasons recorded in the failed login audit log
+	LoginFailureInvalidCredentials     = "invalid_credentials"
+	LoginFailureAccountDisabled        = "account_disabled"
+	LoginFailureInvalidCurrentPassword = "invalid_current_password"
+
+	// loginThrottleIdleTime is how long an entry without failures is kept, consecutive lockouts reset afterwards
+	loginThrottleIdleTime = 24 * time.Hour
+
+	// loginAttemptTimeout releases reserved attempts that were never finished
+	loginAttemptTimeout = 1 * time.Minute
+)
+
+// loginReservation counts attempts in progress for a username, link or IP address
+type loginReservation struct {
+	count int
+	since time.Time
+}
+
+// loginThrottleStore tracks failed logins in memory, since every login attempt checks them
+// Blocked attempts are rejected before pdbedit or smbclient run
+type loginThrottleStore struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]*types.LoginThrottle
+	pending map[string]*loginReservation // Attempts in progress, never written to disk
+}
+
+var loginThrottles = &loginThrottleStore{pending: make(map[string]*loginReservation)}
+
+// loginThrottleKey returns the map key of a username, link or IP address entry
+func loginThrottleKey(kind string, value string) string {
+	return kind + ":" + value
+}
+
+// loadLoginThrottlesInternal reads the throttle file on first use (must hold lock)
+func loadLoginThrottlesInternal() error {
+	if loginThrottles.loaded {
+		return nil
+	}
+
+	var list []types.LoginThrottle
+	if err := readJSONFile(dataFilePath(loginThrottlesFileName), &list); err != nil {
+		return err
+	}
+
+	loginThrottles.entries = make(map[string]*types.LoginThrottle, len(list))
+	for i := range list {
+		loginThrottles.entries[loginThrottleKey(list[i].Kind, list[i].Value)] = &list[i]
+	}
+	loginThrottles.loaded = true
+	return nil
+}
+
+// saveLoginThrottlesInternal drops idle entries and writes the rest (must hold lock)
+func saveLoginThrottlesInternal() error {
+	now := time.Now()
+	list := make([]types.LoginThrottle, 0, len(loginThrottles.entries))
+	for key, entry := range loginThrottles.entries {
+		if now.Sub(entry.LastFailure) > loginThrottleIdleTime && !entry.BlockedUntil.After(now) {
+			delete(loginThrottles.entries, key)
+			continue
+		}
+		list = append(list, *entry)
+	}
+
+	return writeJSONFile(dataFilePath(loginThrottlesFileName), list)
+}
+
+// attemptAvailableInternal reports whether another attempt may start next to those in progress (must hold lock)
+// Attempts in progress count like failures, past the backoff threshold they run one at a time
+func attemptAvailableInternal(key string, maxFailures int, now time.Time) bool {
+	reservation, ok := loginThrottles.pending[key]
+	if !ok {
+		return true
+	}
+	if now.Sub(reservation.since) > loginAttemptTimeout {
+		delete(loginThrottles.pending, key)
+		return true
+	}
+
+	settings := config.AppConfig.LoginProtection
+	failures := 0
+	if entry, ok := loginThrottles.entries[key]; ok && now.Sub(entry.FirstFailure) <= time.Duration(settings.WindowMinutes)*time.Minute {
+		failures = entry.Failures
+	}
+	return failures+reservation.count < min(settings.BackoffAfter, maxFailures)
+}
+
+// releaseAttemptInternal ends an attempt reserved by CheckLoginAllowed (must hold lock)
+func releaseAttemptInternal(key string) {
+	if reservation, ok := loginThrottles.pending[key]; ok {
+		reservation.count--
+		if reservation.count <= 0 {
+			delete(loginThrottles.pending, key)
+		}
+	}
+}
+
+// throttleTarget is a username, link or IP address entry with the failures that lock it out
+type throttleTarget struct {
+	kind        string
+	value       string
+	maxFailures int
+}
+
+// loginTargets returns the entries a login for a username from an IP address counts against
+func loginTargets(username string, ip string) []throttleTarget {
+	settings := config.AppConfig.LoginProtection
+	return []throttleTarget{
+		{kind: types.LoginThrottleUsername, value: username, maxFailures: settings.MaxUserFailures},
+		{kind: types.LoginThrottleIP, value: ip, maxFailures: settings.MaxIPFailures},
+	}
+}
+
+// linkTargets returns the entries a link password attempt from an IP address counts against
+// The IP address entry is shared with logins, so guessing either one slows down the other
+func linkTargets(linkID string, ip string) []throttleTarget {
+	settings := config.AppConfig.LoginProtection
+	return []throttleTarget{
+		{kind: types.LoginThrottleLink, value: linkID, maxFailures: settings.MaxUserFailures},
+		{kind: types.LoginThrottleIP, value: ip, maxFailures: settings.MaxIPFailures},
+	}
+}
+
+// CheckLoginAllowed reports whether a login for a username from an IP address may be attempted and reserves the attempt
+// If not, it returns how long the client has to wait. The reserved attempt counts like a failure until it is finished
+// with RecordLoginFailure, RecordLoginSuccess or ReleaseLoginAttempt, so concurrent guesses can't all pass the check
+func CheckLoginAllowed(username string, ip string) (time.Duration, bool) {
+	return reserveAttempt(loginTargets(username, ip))
+}
+
+// reserveAttempt reserves an attempt against all targets, or returns how long the client has to wait
+func reserveAttempt(targets []throttleTarget) (time.Duration, bool) {
+	loginThrottles.mu.Lock()
+	defer loginThrottles.mu.Unlock()
+
+	if err := loadLoginThrottlesInternal(); err != nil {
+		log.Printf("Failed to read login throttles: %v", err)
+		return 0, true
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, target := range targets {
+		key := loginThrottleKey(target.kind, target.value)
+		if entry, ok := loginThrottles.entries[key]; ok && entry.BlockedUntil.After(now) {
+			wait = max(wait, entry.BlockedUntil.Sub(now))
+		} else if !attemptAvailableInternal(key, target.maxFailures, now) {
+			wait = max(wait, time.Second)
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	for _, target := range targets {
+		key := loginThrottleKey(target.kind, target.value)
+		reservation, ok := loginThrottles.pending[key]
+		if !ok {
+			reservation = &loginReservation{since: now}
+			loginThrottles.pending[key] = reservation
+		}
+		reservation.count++
+	}
+	return 0, true
+}
+
+// ReleaseLoginAttempt ends a reserved attempt without counting it as a failure or a success,
+// e.g. when the password was right but the second factor is still missing
+func ReleaseLoginAttempt(username string, ip string) {
+	loginThrottles.mu.Lock()
+	defer loginThrottles.mu.Unlock()
+
+	releaseAttemptsInternal(loginTargets(username, ip))
+}
+
+// releaseAttemptsInternal ends the attempt reserved against all targets (must hold lock)
+func releaseAttemptsInternal(targets []throttleTarget) {
+	for _, target := range targets {
+		releaseAttemptInternal(loginThrottleKey(target.kind, target.value))
+	}
+}
+
+// recordThrottleFailureInternal counts a failure and sets the resulting backoff or lockout (must hold lock)
+func recordThrottleFailureInternal(kind string, value string, maxFailures int, now time.Time) {
+	settings := config.AppConfig.LoginProtection
+	window := time.Duration(settings.WindowMinutes) * time.Minute
+
+	key := loginThrottleKey(kind, value)
+	entry, ok := loginThrottles.entries[key]
+	if !ok {
+		entry = &types.LoginThrottle{Kind: kind, Value: value}
+		loginThrottles.entries[key] = entry
+	}
+
+	// A long quiet period forgives earlier lockouts
+	if now.Sub(entry.LastFailure) > loginThrottleIdleTime {
+		entry.Lockouts = 0
+	}
+	if entry.Failures == 0 || now.Sub(entry.FirstFailure) > window {
+		entry.Failures = 0
+		entry.FirstFailure = now
+	}
+	entry.Failures++
+	entry.LastFailure = now
+	entry.LockedOut = false
+
+	if entry.Failures >= maxFailures {
+		// Lock out, doubling the length for every consecutive lockout
+		lockout := time.Duration(settings.LockoutMinutes) * time.Minute
+		maxLockout := time.Duration(settings.MaxLockoutMinutes) * time.Minute
+		for i := 0; i < entry.Lockouts && lockout < maxLockout; i++ {
+			lockout *= 2
+		}
+		entry.Lockouts++
+		entry.Failures = 0
+		entry.LockedOut = true
+		entry.BlockedUntil = now.Add(min(lockout, maxLockout))
+		log.Printf("Login locked out for %s %s until %s", kind, value, entry.BlockedUntil.Format(time.RFC3339))
+		return
+	}
+
+	if excess := entry.Failures - settings.BackoffAfter; excess > 0 {
+		// Exponential backoff: 1s, 2s, 4s, ... capped by the window
+		delay := time.Second << min(excess-1, 20)
+		entry.BlockedUntil = now.Add(min(delay, window))
+	}
+}
+
+// RecordLoginFailure counts a failed login for the username and IP address and writes an audit record
+func RecordLoginFailure(username string, ip string, userAgent string, reason string) {
+	now := time.Now()
+	recordFailure(loginTargets(username, ip), now)
+
+	if err := appendLoginFailure(types.LoginFailure{
+		Time:      now,
+		Username:  username,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+	}); err != nil {
+		log.Printf("Failed to record failed login: %v", err)
+	}
+}
+
+// recordFailure ends the reserved attempt and counts a failure against all targets
+func recordFailure(targets []throttleTarget, now time.Time) {
+	loginThrottles.mu.Lock()
+	defer loginThrottles.mu.Unlock()
+
+	releaseAttemptsInternal(targets)
+	if err := loadLoginThrottlesInternal(); err != nil {
+		log.Printf("Failed to read login throttles: %v", err)
+		return
+	}
+	for _, target := range targets {
+		recordThrottleFailureInternal(target.kind, target.value, target.maxFailures, now)
+	}
+	if err := saveLoginThrottlesInternal(); err != nil {
+		log.Printf("Failed to save login throttles: %v", err)
+	}
+}
+
+// RecordLoginSuccess clears the failures of a username after a successful login
+// The IP address keeps its failures, so one known account can't be used to keep guessing others
+func RecordLoginSuccess(username string, ip string) {
+	recordSuccess(loginTargets(username, ip))
+}
+
+// recordSuccess ends the reserved attempt and clears the failures of the first target
+func recordSuccess(targets []throttleTarget) {
+	loginThrottles.mu.Lock()
+	defer loginThrottles.mu.Unlock()
+
+	releaseAttemptsInternal(targets)
+
+	if err := loadLoginThrottlesInternal(); err != nil {
+		log.Printf("Failed to read login throttles: %v", err)
+		return
+	}
+
+	key := loginThrottleKey(targets[0].kind, targets[0].value)
+	if _, ok := loginThrottles.entries[key]; !ok {
+		return
+	}
+	delete(loginThrottles.entries, key)
+	if err := saveLoginThrottlesInternal(); err != nil {
+		log.Printf("Failed to save login throttles: %v", err)
+	}
+}
+
+// CheckLinkPasswordAllowed reports whether a password for a link may be tried from an IP address and reserves the attempt
+// The attempt is finished with RecordLinkPasswordFailure or RecordLinkPasswordSuccess
+func CheckLinkPasswordAllowed(linkID string, ip string) (time.Duration, bool) {
+	return reserveAttempt(linkTargets(linkID, ip))
+}
+
+// RecordLinkPasswordFailure counts a wrong link password for the link and IP address
+func RecordLinkPasswordFailure(linkID string, ip string) {
+	recordFailure(linkTargets(linkID, ip), time.Now())
+}
+
+// RecordLinkPasswordSuccess clears the failures of a link after its password was entered correctly
+func RecordLinkPasswordSuccess(linkID string, ip string) {
+	recordSuccess(linkTargets(linkID, ip))
+}
+
+// ListLoginThrottles lists usernames, links and IP addresses with recent failures, blocked ones first
+func ListLoginThrottles() ([]types.LoginThrottle, error) {
+	loginThrottles.mu.Lock()
+	defer loginThrottles.mu.Unlock()
+
+	if err := loadLoginThrottlesInternal(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	list := make([]types.LoginThrottle, 0, len(loginThrottles.entries))
+	for _, entry := range loginThrottles.entries {
+		throttle := *entry
+		if !throttle.BlockedUntil.After(now) {
+			throttle.LockedOut = false
+		}
+		list = append(list, throttle)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].BlockedUntil.After(now) != list[j].BlockedUntil.After(now) {
+			return list[i].BlockedUntil.After(now)
+		}
+		return list[i].LastFailure.After(list[j].LastFailure)
+	})
+
+	return list, nil
+}
+
+// UnlockLogin clears the failures, backoff and lockout of a username, link or IP address
+func UnlockLogin(kind string, value string) error {
+	loginThrottles.mu.Lock()
+	defer loginThrottles.mu.Unlock()
+
+	if err := loadLoginThrottlesInternal(); err != nil {
+		return err
+	}
+
+	key := loginThrottleKey(kind, value)
+	if _, ok := loginThrottles.entries[key]; !ok {
+		return utils.NewNotFoundError("No failed logins recorded")
+	}
+	delete(loginThrottles.entries, key)
+	return saveLoginThrottlesInternal()
+}
+
+// loginFailuresMu guards the failed login audit log
+var loginFailuresMu sync.Mutex
+
+// appendLoginFailure adds a record to the failed login audit log, dropping the oldest beyond the limit
+func appendLoginFailure(failure types.LoginFailure) error {
+	loginFailuresMu.Lock()
+	defer loginFailuresMu.Unlock()
+
+	var failures []types.LoginFailure
+	if err := readJSONFile(dataFilePath(loginFailuresFileName), &failures); err != nil {
+		return err
+	}
+
+	failures = append(failures, failure)
+	if len(failures) > maxLoginFailureRecords {
+		failures = failures[len(failures)-maxLoginFailureRecords:]
+	}
+
+	return writeJSONFile(dataFilePath(loginFailuresFileName), failures)
+}
+
+// ListLoginFailures returns failed login records matching the query, newest first
+func ListLoginFailures(query *types.LoginFailureQuery) ([]types.LoginFailure, error) {
+	loginFailuresMu.Lock()
+	defer loginFailuresMu.Unlock()
+
+	var failures []types.LoginFailure
+	if err := readJSONFile(dataFilePath(loginFailuresFileName), &failures); err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 200
+	}
+
+	result := []types.LoginFailure{}
+	for i := len(failures) - 1; i >= 0 && len(result) < limit; i-- {
+		failure := failures[i]
+		if (query.Username == "" || failure.Username == query.Username) && (query.IP == "" || failure.IP == query.IP) {
+			result = append(result, failure)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+)
+
+// setupLoginProtection uses a fresh data directory and throttle store with fixed settings
+func setupLoginProtection(t *testing.T) {
+	t.Helper()
+
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig = &config.Config{DataDir: t.TempDir()}
+	settings := &config.AppConfig.LoginProtection
+	settings.BackoffAfter = 3
+	settings.MaxUserFailures = 5
+	settings.MaxIPFailures = 10
+	settings.WindowMinutes = 15
+	settings.LockoutMinutes = 15
+	settings.MaxLockoutMinutes = 60
+
+	loginThrottles.mu.Lock()
+	loginThrottles.loaded = true
+	loginThrottles.entries = make(map[string]*types.LoginThrottle)
+	loginThrottles.pending = make(map[string]*loginReservation)
+	loginThrottles.mu.Unlock()
+}
+
+func TestRecordThrottleFailure(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		entry       *types.LoginThrottle // Entry before the failure, nil for none
+		maxFailures int
+
+		wantFailures  int
+		wantBlocked   time.Duration // Block from now, 0 for none
+		wantLockouts  int
+		wantLockedOut bool
+	}{
+		{
+			name:         "first failure",
+			maxFailures:  5,
+			wantFailures: 1,
+		},
+		{
+			name:         "up to the backoff threshold",
+			entry:        &types.LoginThrottle{Failures: 2, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute)},
+			maxFailures:  5,
+			wantFailures: 3,
+		},
+		{
+			name:         "first backoff",
+			entry:        &types.LoginThrottle{Failures: 3, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute)},
+			maxFailures:  10,
+			wantFailures: 4,
+			wantBlocked:  time.Second,
+		},
+		{
+			name:         "backoff doubles",
+			entry:        &types.LoginThrottle{Failures: 5, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute)},
+			maxFailures:  10,
+			wantFailures: 6,
+			wantBlocked:  4 * time.Second,
+		},
+		{
+			name:         "backoff capped by the window",
+			entry:        &types.LoginThrottle{Failures: 40, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute)},
+			maxFailures:  100,
+			wantFailures: 41,
+			wantBlocked:  15 * time.Minute,
+		},
+		{
+			name:          "lockout at max failures",
+			entry:         &types.LoginThrottle{Failures: 4, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute)},
+			maxFailures:   5,
+			wantFailures:  0,
+			wantBlocked:   15 * time.Minute,
+			wantLockouts:  1,
+			wantLockedOut: true,
+		},
+		{
+			name:          "consecutive lockout doubles",
+			entry:         &types.LoginThrottle{Failures: 4, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute), Lockouts: 1},
+			maxFailures:   5,
+			wantFailures:  0,
+			wantBlocked:   30 * time.Minute,
+			wantLockouts:  2,
+			wantLockedOut: true,
+		},
+		{
+			name:          "lockout capped by the maximum",
+			entry:         &types.LoginThrottle{Failures: 4, FirstFailure: now.Add(-time.Minute), LastFailure: now.Add(-time.Minute), Lockouts: 5},
+			maxFailures:   5,
+			wantFailures:  0,
+			wantBlocked:   60 * time.Minute,
+			wantLockouts:  6,
+			wantLockedOut: true,
+		},
+		{
+			name:         "failures outside the window are forgotten",
+			entry:        &types.LoginThrottle{Failures: 4, FirstFailure: now.Add(-20 * time.Minute), LastFailure: now.Add(-16 * time.Minute)},
+			maxFailures:  5,
+			wantFailures: 1,
+		},
+		{
+			name:         "lockouts are kept within the idle time",
+			entry:        &types.LoginThrottle{Failures: 0, LastFailure: now.Add(-time.Hour), Lockouts: 2},
+			maxFailures:  5,
+			wantFailures: 1,
+			wantLockouts: 2,
+		},
+		{
+			name:          "lockouts reset after the idle time",
+			entry:         &types.LoginThrottle{Failures: 0, LastFailure: now.Add(-25 * time.Hour), Lockouts: 3},
+			maxFailures:   1,
+			wantFailures:  0,
+			wantBlocked:   15 * time.Minute,
+			wantLockouts:  1,
+			wantLockedOut: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupLoginProtection(t)
+			key := loginThrottleKey(types.LoginThrottleUsername, "alice")
+			if tt.entry != nil {
+				tt.entry.Kind = types.LoginThrottleUsername
+				tt.entry.Value = "alice"
+				loginThrottles.entries[key] = tt.entry
+			}
+
+			recordThrottleFailureInternal(types.LoginThrottleUsername, "alice", tt.maxFailures, now)
+
+			entry := loginThrottles.entries[key]
+			if entry == nil {
+				t.Fatal("no entry recorded")
+			}
+			if entry.Failures != tt.wantFailures {
+				t.Errorf("Failures = %d, want %d", entry.Failures, tt.wantFailures)
+			}
+			var wantBlockedUntil time.Time
+			if tt.wantBlocked > 0 {
+				wantBlockedUntil = now.Add(tt.wantBlocked)
+			}
+			if !entry.BlockedUntil.Equal(wantBlockedUntil) {
+				t.Errorf("BlockedUntil = %s, want %s", entry.BlockedUntil, wantBlockedUntil)
+			}
+			if entry.Lockouts != tt.wantLockouts {
+				t.Errorf("Lockouts = %d, want %d", entry.Lockouts, tt.wantLockouts)
+			}
+			if entry.LockedOut != tt.wantLockedOut {
+				t.Errorf("LockedOut = %v, want %v", entry.LockedOut, tt.wantLockedOut)
+			}
+			if !entry.LastFailure.Equal(now) {
+				t.Errorf("LastFailure = %s, want %s", entry.LastFailure, now)
+			}
+		})
+	}
+}
+
+func TestCheckLoginAllowedLimitsConcurrentAttempts(t *testing.T) {
+	setupLoginProtection(t)
+
+	// Attempts in progress count like failures, so only BackoffAfter of them may run at once
+	for i := 0; i < 3; i++ {
+		if _, ok := CheckLoginAllowed("alice", "192.0.2.1"); !ok {
+			t.Fatalf("attempt %d rejected", i+1)
+		}
+	}
+	if wait, ok := CheckLoginAllowed("alice", "192.0.2.1"); ok || wait <= 0 {
+		t.Fatalf("CheckLoginAllowed = (%s, %v), want a wait", wait, ok)
+	}
+
+	ReleaseLoginAttempt("alice", "192.0.2.1")
+	if _, ok := CheckLoginAllowed("alice", "192.0.2.1"); !ok {
+		t.Fatal("attempt rejected after another one was released")
+	}
+}
+
+func TestRecordLoginSuccessKeepsIPFailures(t *testing.T) {
+	setupLoginProtection(t)
+
+	for i := 0; i < 2; i++ {
+		if _, ok := CheckLoginAllowed("alice", "192.0.2.1"); !ok {
+			t.Fatalf("attempt %d rejected", i+1)
+		}
+		RecordLoginFailure("alice", "192.0.2.1", "test", LoginFailureInvalidCredentials)
+	}
+	if _, ok := CheckLoginAllowed("alice", "192.0.2.1"); !ok {
+		t.Fatal("attempt rejected")
+	}
+	RecordLoginSuccess("alice", "192.0.2.1")
+
+	if _, ok := loginThrottles.entries[loginThrottleKey(types.LoginThrottleUsername, "alice")]; ok {
+		t.Error("username failures kept after a successful login")
+	}
+	entry, ok := loginThrottles.entries[loginThrottleKey(types.LoginThrottleIP, "192.0.2.1")]
+	if !ok || entry.Failures != 2 {
+		t.Errorf("IP address entry = %+v, want 2 failures", entry)
+	}
+	if len(loginThrottles.pending) != 0 {
+		t.Errorf("attempts still reserved: %v", loginThrottles.pending)
+	}
+}
+
+func TestLinkPasswordFailuresCountAgainstIP(t *testing.T) {
+	setupLoginProtection(t)
+
+	// The link is locked out after MaxUserFailures wrong passwords
+	for i := 0; i < 5; i++ {
+		// Skip the backoff delays past the threshold
+		for _, entry := range loginThrottles.entries {
+			entry.BlockedUntil = time.Time{}
+		}
+		if _, ok := CheckLinkPasswordAllowed("link-1", "192.0.2.1"); !ok {
+			t.Fatalf("attempt %d rejected", i+1)
+		}
+		RecordLinkPasswordFailure("link-1", "192.0.2.1")
+	}
+
+	link := loginThrottles.entries[loginThrottleKey(types.LoginThrottleLink, "link-1")]
+	if link == nil || !link.LockedOut {
+		t.Fatalf("link entry = %+v, want a lockout", link)
+	}
+	if _, ok := CheckLinkPasswordAllowed("link-1", "192.0.2.2"); ok {
+		t.Error("locked out link accepted a password from another IP address")
+	}
+
+	ip := loginThrottles.entries[loginThrottleKey(types.LoginThrottleIP, "192.0.2.1")]
+	if ip == nil || ip.Failures != 5 {
+		t.Errorf("IP address entry = %+v, want 5 failures", ip)
+	}
+}
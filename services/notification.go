@@ -0,0 +1,131 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	notificationsFileName = "notifications.json"
+
+	// maxNotifications is how many notifications are kept, the oldest are dropped first
+	maxNotifications = 200
+)
+
+// NotificationService stores notifications shown to administrators
+type NotificationService struct {
+	mu sync.Mutex
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService() *NotificationService {
+	return &NotificationService{}
+}
+
+// loadNotifications reads all notifications (must hold lock)
+func (s *NotificationService) loadNotifications() ([]types.Notification, error) {
+	notifications := []types.Notification{}
+	if err := readJSONFile(dataFilePath(notificationsFileName), &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// Notify records a new notification for administrators
+func (s *NotificationService) Notify(title string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.Printf("%s: %s", title, message)
+
+	notifications, err := s.loadNotifications()
+	if err != nil {
+		return err
+	}
+
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return fmt.Errorf("failed to generate notification ID: %v", err)
+	}
+
+	notifications = append(notifications, types.Notification{
+		ID:        hex.EncodeToString(bytes),
+		Title:     title,
+		Message:   message,
+		CreatedAt: time.Now(),
+	})
+	if len(notifications) > maxNotifications {
+		notifications = notifications[len(notifications)-maxNotifications:]
+	}
+
+	return writeJSONFile(dataFilePath(notificationsFileName), notifications)
+}
+
+// ListNotifications returns all notifications, newest first
+func (s *NotificationService) ListNotifications() ([]types.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications, err := s.loadNotifications()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+
+	return notifications, nil
+}
+
+// MarkRead marks a notification as read (an empty ID marks all of them)
+func (s *NotificationService) MarkRead(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications, err := s.loadNotifications()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range notifications {
+		if id == "" || notifications[i].ID == id {
+			notifications[i].Read = true
+			found = true
+		}
+	}
+	if id != "" && !found {
+		return utils.NewNotFoundError("Notification not found")
+	}
+
+	return writeJSONFile(dataFilePath(notificationsFileName), notifications)
+}
+
+// DeleteNotification removes a notification
+func (s *NotificationService) DeleteNotification(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifications, err := s.loadNotifications()
+	if err != nil {
+		return err
+	}
+
+	for i, notification := range notifications {
+		if notification.ID == id {
+			notifications = append(notifications[:i], notifications[i+1:]...)
+			return writeJSONFile(dataFilePath(notificationsFileName), notifications)
+		}
+	}
+
+	return utils.NewNotFoundError("Notification not found")
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	oidcStateLifetime     = 10 * time.Minute
+	oidcLoginCodeLifetime = 1 * time.Minute
+	oidcMetadataLifetime  = 1 * time.Hour   // Discovery document and keys are fetched again afterwards
+	oidcKeyRefreshDelay   = 1 * time.Minute // Minimum time between key refreshes for unknown key IDs
+	oidcClockSkew         = 1 * time.Minute
+	oidcHTTPTimeout       = 10 * time.Second
+)
+
+// oidcSigningMethods are the ID token algorithms accepted, symmetric and "none" never are
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// oidcMetadata is the part of the provider's discovery document that is used
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcPendingLogin is an authorization request waiting for the provider's callback
+type oidcPendingLogin struct {
+	nonce        string
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+// oidcLoginCode hands a completed login from the callback to the web UI
+type oidcLoginCode struct {
+	username  string
+	expiresAt time.Time
+}
+
+// OIDCService signs users in with an OpenID Connect identity provider
+// (authorization code flow with PKCE, the ID token is validated against the provider's keys)
+type OIDCService struct {
+	mu            sync.Mutex
+	client        *http.Client
+	metadata      *oidcMetadata
+	keys          map[string]crypto.PublicKey
+	fetchedAt     time.Time
+	keysFetchedAt time.Time
+	pending       map[string]*oidcPendingLogin // By state
+	loginCodes    map[string]*oidcLoginCode    // By code hash
+}
+
+// NewOIDCService creates a new OIDC service
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		client:     &http.Client{Timeout: oidcHTTPTimeout},
+		pending:    make(map[string]*oidcPendingLogin),
+		loginCodes: make(map[string]*oidcLoginCode),
+	}
+}
+
+// Enabled reports whether single sign-on is configured
+func (s *OIDCService) Enabled() bool {
+	return config.AppConfig.OIDC.Enabled
+}
+
+// getJSON fetches a JSON document from the provider
+func (s *OIDCService) getJSON(rawURL string, v interface{}) error {
+	resp, err := s.client.Get(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: status %d", rawURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", rawURL, err)
+	}
+	return nil
+}
+
+// discoverInternal loads the discovery document and signing keys when missing or outdated (must hold lock)
+func (s *OIDCService) discoverInternal() error {
+	if s.metadata != nil && time.Since(s.fetchedAt) < oidcMetadataLifetime {
+		return nil
+	}
+
+	issuer := strings.TrimSuffix(config.AppConfig.OIDC.Issuer, "/")
+	var metadata oidcMetadata
+	if err := s.getJSON(issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return err
+	}
+	// The discovery document must belong to the configured issuer (OpenID Connect Discovery 4.3)
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return fmt.Errorf("discovery document issuer %q does not match %q", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return fmt.Errorf("discovery document is missing endpoints")
+	}
+
+	keys, err := s.fetchKeys(metadata.JWKSURI)
+	if err != nil {
+		return err
+	}
+
+	s.metadata = &metadata
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.keysFetchedAt = s.fetchedAt
+	return nil
+}
+
+// jsonWebKey is a public key from the provider's JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes an RSA or EC key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(value string) (*big.Int, error) {
+		bytes, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(bytes) == 0 {
+			return nil, fmt.Errorf("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(bytes), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// fetchKeys loads the signing keys of the provider by key ID
+func (s *OIDCService) fetchKeys(jwksURI string) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := s.getJSON(jwksURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Skipping OIDC signing key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable signing keys at %s", jwksURI)
+	}
+	return keys, nil
+}
+
+// signingKey returns the key for an ID token, refreshing the keys once if the provider rotated them
+func (s *OIDCService) signingKey(token *jwt.Token) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// Without a key ID every key is tried
+		set := jwt.VerificationKeySet{}
+		for _, key := range s.keys {
+			set.Keys = append(set.Keys, key)
+		}
+		return set, nil
+	}
+
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if s.metadata != nil && time.Since(s.keysFetchedAt) >= oidcKeyRefreshDelay {
+		s.keysFetchedAt = time.Now()
+		if keys, err := s.fetchKeys(s.metadata.JWKSURI); err == nil {
+			s.keys = keys
+		} else {
+			log.Printf("Failed to refresh OIDC signing keys: %v", err)
+		}
+		if key, ok := s.keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// randomToken returns a random URL-safe string
+func randomToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// BeginLogin starts an authorization request and returns its state and the provider URL to send the browser to
+func (s *OIDCService) BeginLogin() (string, string, error) {
+	if !s.Enabled() {
+		return "", "", utils.NewNotFoundError("Single sign-on is not enabled")
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.discoverInternal(); err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	for key, pending := range s.pending {
+		if !pending.expiresAt.After(now) {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = &oidcPendingLogin{
+		nonce:        nonce,
+		codeVerifier: codeVerifier,
+		expiresAt:    now.Add(oidcStateLifetime),
+	}
+
+	// PKCE with S256 (RFC 7636)
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	settings := config.AppConfig.OIDC
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", settings.ClientID)
+	params.Set("redirect_uri", settings.RedirectURL)
+	params.Set("scope", strings.Join(settings.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(s.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return state, s.metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// CompleteLogin handles the provider's callback: it exchanges the code, validates the ID token
+// and maps its claims to an existing Samba user, applying mapped admin roles
+// Returns the username and whether the user's admin roles changed
+func (s *OIDCService) CompleteLogin(state string, code string) (string, bool, error) {
+	s.mu.Lock()
+	pending, ok := s.pending[state]
+	delete(s.pending, state)
+	var tokenEndpoint, issuer string
+	err := s.discoverInternal()
+	if err == nil {
+		tokenEndpoint = s.metadata.TokenEndpoint
+		issuer = s.metadata.Issuer
+	}
+	s.mu.Unlock()
+
+	if !ok || !pending.expiresAt.After(time.Now()) {
+		return "", false, utils.NewUnauthorizedError("Sign-in expired, please try again")
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	rawIDToken, err := s.exchangeCode(tokenEndpoint, code, pending.codeVerifier)
+	if err != nil {
+		return "", false, err
+	}
+
+	claims, err := s.verifyIDToken(rawIDToken, issuer, pending.nonce)
+	if err != nil {
+		log.Printf("Rejected OIDC ID token: %v", err)
+		return "", false, utils.NewUnauthorizedError("Invalid ID token")
+	}
+
+	username, err := oidcUsername(claims)
+	if err != nil {
+		return "", false, err
+	}
+
+	rolesChanged := false
+	if config.AppConfig.OIDC.RolesClaim != "" {
+		if rolesChanged, err = syncOIDCAdminRoles(username, oidcAdminRoles(claims)); err != nil {
+			return "", false, err
+		}
+	}
+
+	return username, rolesChanged, nil
+}
+
+// exchangeCode redeems an authorization code at the token endpoint and returns the ID token
+func (s *OIDCService) exchangeCode(tokenEndpoint string, code string, codeVerifier string) (string, error) {
+	settings := config.AppConfig.OIDC
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", settings.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", settings.ClientID)
+
+	req, err := http.NewRequest(http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if settings.ClientSecret != "" {
+		// client_secret_basic, both parts form-encoded (RFC 6749 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(settings.ClientID), url.QueryEscape(settings.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach token endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		log.Printf("OIDC token request failed: status %d, %s %s", resp.StatusCode, result.Error, result.ErrorDescription)
+		return "", utils.NewUnauthorizedError("The identity provider rejected the sign-in")
+	}
+	if result.IDToken == "" {
+		return "", fmt.Errorf("token response contains no ID token")
+	}
+	return result.IDToken, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce of an ID token
+func (s *OIDCService) verifyIDToken(rawIDToken string, issuer string, nonce string) (jwt.MapClaims, error) {
+	clientID := config.AppConfig.OIDC.ClientID
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, s.signingKey,
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("nonce mismatch")
+	}
+	// With several audiences the authorized party has to be this client
+	if azp, ok := claims["azp"].(string); ok && azp != clientID {
+		return nil, fmt.Errorf("authorized party %q is not this client", azp)
+	}
+	return claims, nil
+}
+
+// oidcClaim looks up a claim, dots in the path reach into nested objects
+func oidcClaim(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// oidcUsername maps the ID token claims to an existing, enabled Samba user
+func oidcUsername(claims jwt.MapClaims) (string, error) {
+	settings := config.AppConfig.OIDC
+	// Unverified addresses could be set to anyone's
+	if settings.UsernameClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return "", utils.NewForbiddenError("The identity provider has not verified the email address")
+		}
+	}
+	username, _ := oidcClaim(claims, settings.UsernameClaim).(string)
+	if settings.StripDomain {
+		username, _, _ = strings.Cut(username, "@")
+	}
+
+	if !isValidUsername(username) {
+		return "", utils.NewForbiddenError("The identity provider did not supply a valid username")
+	}
+	if exists, active := GetUserStatus(username); !exists || !active {
+		return "", utils.NewForbiddenError(fmt.Sprintf("No active account for %s", username))
+	}
+	return username, nil
+}
+
+// oidcAdminRoles maps the values of the roles claim to admin roles
+func oidcAdminRoles(claims jwt.MapClaims) []string {
+	settings := config.AppConfig.OIDC
+
+	var values []string
+	switch claim := oidcClaim(claims, settings.RolesClaim).(type) {
+	case string:
+		values = strings.Fields(claim)
+	case []interface{}:
+		for _, item := range claim {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+
+	var roles []string
+	for _, value := range values {
+		roles = append(roles, settings.RoleMapping[value]...)
+	}
+	return roles
+}
+
+// IssueLoginCode returns a single-use code the web UI exchanges for its tokens
+// Tokens are never put into a redirect URL, where they would end up in the browser history
+func (s *OIDCService) IssueLoginCode(username string) (string, error) {
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for hash, loginCode := range s.loginCodes {
+		if !loginCode.expiresAt.After(now) {
+			delete(s.loginCodes, hash)
+		}
+	}
+	s.loginCodes[hashSessionSecret(code)] = &oidcLoginCode{
+		username:  username,
+		expiresAt: now.Add(oidcLoginCodeLifetime),
+	}
+	return code, nil
+}
+
+// RedeemLoginCode consumes a login code and returns its user
+func (s *OIDCService) RedeemLoginCode(code string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := hashSessionSecret(code)
+	loginCode, ok := s.loginCodes[hash]
+	delete(s.loginCodes, hash)
+	if !ok || !loginCode.expiresAt.After(time.Now()) {
+		return "", false
+	}
+	return loginCode.username, true
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// fakePdbedit answers "pdbedit -L -v -u <user>" for an active user alice and a disabled user bob
+const fakePdbedit = `#!/bin/sh
+for user; do :; done
+case "$user" in
+alice) printf 'Unix username:        alice\nAccount Flags:        [U          ]\n' ;;
+bob) printf 'Unix username:        bob\nAccount Flags:        [DU         ]\n' ;;
+*) echo "Username not found!" >&2; exit 1 ;;
+esac
+`
+
+// testProvider is an OpenID Connect provider serving discovery, JWKS and a token endpoint
+type testProvider struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PrivateKey // Published signing keys by key ID
+	challenge string                     // PKCE challenge of the pending authorization request
+	idToken   string                     // ID token returned by the next token request
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+
+	p := &testProvider{keys: make(map[string]*rsa.PrivateKey)}
+	p.addKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		var keys []map[string]string
+		for kid, key := range p.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("grant_type") != "authorization_code" || base64.RawURLEncoding.EncodeToString(verifier[:]) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": p.idToken})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// addKey generates and publishes a signing key
+func (p *testProvider) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.keys[kid] = key
+	p.mu.Unlock()
+	return key
+}
+
+// sign returns an RS256 ID token signed with a published key
+func (p *testProvider) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	p.mu.Lock()
+	key := p.keys[kid]
+	p.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// setupOIDCTest points the configuration at a test provider and a temporary data directory
+func setupOIDCTest(t *testing.T) (*OIDCService, *testProvider) {
+	t.Helper()
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "pdbedit"), []byte(fakePdbedit), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	p := newTestProvider(t)
+
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig = &config.Config{DataDir: t.TempDir()}
+	settings := &config.AppConfig.OIDC
+	settings.Enabled = true
+	settings.Issuer = p.server.URL
+	settings.ClientID = "samba-manager"
+	settings.RedirectURL = "https://samba.example.com/api/oidc/callback"
+	settings.Scopes = []string{"openid", "profile"}
+	settings.UsernameClaim = "preferred_username"
+	settings.RolesClaim = "groups"
+	settings.RoleMapping = map[string][]string{"samba-admins": {types.AdminRoleFull}}
+
+	return NewOIDCService(), p
+}
+
+// beginTestLogin starts an authorization request and returns its state and nonce
+func beginTestLogin(t *testing.T, s *OIDCService, p *testProvider) (string, string) {
+	t.Helper()
+
+	state, authURL, err := s.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("state") != state || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization URL %s", authURL)
+	}
+
+	p.mu.Lock()
+	p.challenge = query.Get("code_challenge")
+	p.mu.Unlock()
+	return state, query.Get("nonce")
+}
+
+// validClaims returns the claims of an ID token the service accepts
+func validClaims(p *testProvider, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":                p.server.URL,
+		"sub":                "0f3c9a",
+		"aud":                "samba-manager",
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"preferred_username": "alice",
+	}
+}
+
+// completeTestLogin finishes a login with the ID token built by token
+func completeTestLogin(t *testing.T, s *OIDCService, p *testProvider, token func(nonce string) string) (string, bool, error) {
+	t.Helper()
+
+	state, nonce := beginTestLogin(t, s, p)
+	idToken := token(nonce)
+	p.mu.Lock()
+	p.idToken = idToken
+	p.mu.Unlock()
+
+	return s.CompleteLogin(state, "authorization-code")
+}
+
+func TestOIDCIDTokenValidation(t *testing.T) {
+	s, p := setupOIDCTest(t)
+
+	signed := func(change func(claims jwt.MapClaims)) func(nonce string) string {
+		return func(nonce string) string {
+			claims := validClaims(p, nonce)
+			change(claims)
+			return p.sign(t, "key-1", claims)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		token   func(nonce string) string
+		wantErr bool
+	}{
+		{"valid", signed(func(jwt.MapClaims) {}), false},
+		{"nonce mismatch", signed(func(c jwt.MapClaims) { c["nonce"] = "other-nonce" }), true},
+		{"missing nonce", signed(func(c jwt.MapClaims) { delete(c, "nonce") }), true},
+		{"wrong audience", signed(func(c jwt.MapClaims) { c["aud"] = "other-client" }), true},
+		{"wrong authorized party", signed(func(c jwt.MapClaims) {
+			c["aud"] = []string{"samba-manager", "other-client"}
+			c["azp"] = "other-client"
+		}), true},
+		{"wrong issuer", signed(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }), true},
+		{"expired", signed(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }), true},
+		{"missing expiry", signed(func(c jwt.MapClaims) { delete(c, "exp") }), true},
+		{"unknown key", func(nonce string) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims(p, nonce))
+			token.Header["kid"] = "key-1"
+			key, _ := rsa.GenerateKey(rand.Reader, 2048)
+			raw, _ := token.SignedString(key)
+			return raw
+		}, true},
+		{"alg none", func(nonce string) string {
+			raw, _ := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(p, nonce)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return raw
+		}, true},
+		{"HS256 with the client ID as secret", func(nonce string) string {
+			raw, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(p, nonce)).SignedString([]byte("samba-manager"))
+			return raw
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, _, err := completeTestLogin(t, s, p, tt.token)
+			if tt.wantErr {
+				var unauthorizedErr *utils.UnauthorizedError
+				if !errors.As(err, &unauthorizedErr) {
+					t.Fatalf("expected an invalid ID token error, got username %q, error %v", username, err)
+				}
+				return
+			}
+			if err != nil || username != "alice" {
+				t.Fatalf("expected alice, got %q, %v", username, err)
+			}
+		})
+	}
+}
+
+func TestOIDCStateIsSingleUse(t *testing.T) {
+	s, p := setupOIDCTest(t)
+
+	state, nonce := beginTestLogin(t, s, p)
+	p.idToken = p.sign(t, "key-1", validClaims(p, nonce))
+	if _, _, err := s.CompleteLogin(state, "authorization-code"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.CompleteLogin(state, "authorization-code"); err == nil {
+		t.Fatal("a state was accepted twice")
+	}
+}
+
+func TestOIDCUsernameClaim(t *testing.T) {
+	s, p := setupOIDCTest(t)
+	config.AppConfig.OIDC.UsernameClaim = "email"
+	config.AppConfig.OIDC.StripDomain = true
+
+	tests := []struct {
+		name     string
+		email    string
+		verified interface{} // Value of email_verified, nil leaves it out
+		want     string
+	}{
+		{"verified email", "alice@example.com", true, "alice"},
+		{"unverified email", "alice@example.com", false, ""},
+		{"email_verified missing", "alice@example.com", nil, ""},
+		{"email_verified as string", "alice@example.com", "true", ""},
+		{"disabled account", "bob@example.com", true, ""},
+		{"unknown account", "carol@example.com", true, ""},
+		{"invalid username", "al ice@example.com", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, _, err := completeTestLogin(t, s, p, func(nonce string) string {
+				claims := validClaims(p, nonce)
+				delete(claims, "preferred_username")
+				claims["email"] = tt.email
+				if tt.verified != nil {
+					claims["email_verified"] = tt.verified
+				}
+				return p.sign(t, "key-1", claims)
+			})
+			if tt.want == "" {
+				var forbiddenErr *utils.ForbiddenError
+				if !errors.As(err, &forbiddenErr) {
+					t.Fatalf("expected the login to be refused, got username %q, error %v", username, err)
+				}
+				return
+			}
+			if err != nil || username != tt.want {
+				t.Fatalf("expected %s, got %q, %v", tt.want, username, err)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	s, p := setupOIDCTest(t)
+
+	// The first login caches key-1
+	if _, _, err := completeTestLogin(t, s, p, func(nonce string) string {
+		return p.sign(t, "key-1", validClaims(p, nonce))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The provider rotates to key-2, right after the last fetch the keys aren't fetched again
+	p.addKey(t, "key-2")
+	p.mu.Lock()
+	delete(p.keys, "key-1")
+	p.mu.Unlock()
+	if _, _, err := completeTestLogin(t, s, p, func(nonce string) string {
+		return p.sign(t, "key-2", validClaims(p, nonce))
+	}); err == nil {
+		t.Fatal("an unknown key ID fetched the keys again before the refresh delay passed")
+	}
+
+	// After the delay an unknown key ID fetches the new keys
+	s.mu.Lock()
+	s.keysFetchedAt = time.Now().Add(-oidcKeyRefreshDelay)
+	s.mu.Unlock()
+	if _, _, err := completeTestLogin(t, s, p, func(nonce string) string {
+		return p.sign(t, "key-2", validClaims(p, nonce))
+	}); err != nil {
+		t.Fatalf("rotated key was not picked up: %v", err)
+	}
+
+	// The removed key is no longer trusted
+	s.mu.Lock()
+	_, stillTrusted := s.keys["key-1"]
+	s.mu.Unlock()
+	if stillTrusted {
+		t.Fatal("the removed key is still trusted")
+	}
+}
+
+func TestOIDCRoleMapping(t *testing.T) {
+	s, p := setupOIDCTest(t)
+
+	login := func(groups ...string) bool {
+		t.Helper()
+		_, changed, err := completeTestLogin(t, s, p, func(nonce string) string {
+			claims := validClaims(p, nonce)
+			claims["groups"] = groups
+			return p.sign(t, "key-1", claims)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return changed
+	}
+	roles := func() []string {
+		t.Helper()
+		roles, _ := GetSambaAdminRoles("alice")
+		return roles
+	}
+
+	// A mapped group grants the admin role
+	if !login("staff", "samba-admins") || !sameRoles(roles(), []string{types.AdminRoleFull}) {
+		t.Fatalf("mapped group did not grant the admin role, roles %v", roles())
+	}
+	// Signing in again with the same groups changes nothing
+	if login("samba-admins") {
+		t.Fatal("unchanged roles were reported as changed")
+	}
+	// Leaving the group revokes the role granted through the provider
+	if !login("staff") || roles() != nil {
+		t.Fatalf("role granted through the provider was not revoked, roles %v", roles())
+	}
+
+	// A grant made by an admin stays when the groups map to no role
+	if err := updateSambaAdmins(func(grants map[string]types.SambaAdminGrant) error {
+		grants["alice"] = types.SambaAdminGrant{Roles: []string{types.AdminRoleAuditor}, GrantedBy: "root"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if login("staff") || !sameRoles(roles(), []string{types.AdminRoleAuditor}) {
+		t.Fatalf("grant made by an admin was changed, roles %v", roles())
+	}
+}
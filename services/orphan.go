@@ -7,9 +7,11 @@ import (
 
 	"github.com/itsHenry35/SambaManager/config"
 	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
 )
 
 // ListOrphanedDirectories lists home directories that don't have corresponding Samba users
+// Sizes come from the background calculator, so the call never walks the directory trees
 func (s *SambaService) ListOrphanedDirectories() ([]types.OrphanedDirectory, error) {
 	// Get all Samba users
 	users, err := s.ListUsers()
@@ -36,56 +38,126 @@ func (s *SambaService) ListOrphanedDirectories() ([]types.OrphanedDirectory, err
 			dirName := entry.Name()
 			// If directory name doesn't match any existing user, it's orphaned
 			if !userMap[dirName] {
-				dirPath := filepath.Join(homeDir, dirName)
-
-				// Get directory size
-				var size int64
-				err := filepath.Walk(dirPath, func(_ string, info os.FileInfo, err error) error {
-					if err != nil {
-						// Skip files/dirs that can't be accessed
-						return nil
-					}
-					if !info.IsDir() {
-						size += info.Size()
-					}
-					return nil
-				})
-				// If walk fails, set size to 0 and continue
+				info, err := entry.Info()
 				if err != nil {
-					size = 0
+					continue
 				}
 
-				orphanedDirs = append(orphanedDirs, types.OrphanedDirectory{
-					Name: dirName,
-					Path: dirPath,
-					Size: size,
-				})
+				orphanedDir := types.OrphanedDirectory{
+					Name:         dirName,
+					Path:         filepath.Join(homeDir, dirName),
+					LastModified: info.ModTime(),
+				}
+				s.orphanSizes.Lookup(&orphanedDir)
+				orphanedDirs = append(orphanedDirs, orphanedDir)
 			}
 		}
 	}
 
+	// Track how long each directory has been orphaned for the retention policy
+	dirNames := make([]string, 0, len(orphanedDirs))
+	for _, dir := range orphanedDirs {
+		dirNames = append(dirNames, dir.Name)
+	}
+	firstSeen, err := trackOrphansFirstSeen(dirNames)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orphanedDirs {
+		seenAt := firstSeen[orphanedDirs[i].Name]
+		orphanedDirs[i].FirstSeenAt = &seenAt
+	}
+
 	return orphanedDirs, nil
 }
 
-// DeleteOrphanedDirectory deletes an orphaned home directory
-func (s *SambaService) DeleteOrphanedDirectory(dirName string) error {
+// GetOrphanSizeStatus returns the progress of the background size calculation
+func (s *SambaService) GetOrphanSizeStatus() types.OrphanSizeJobStatus {
+	return s.orphanSizes.Status()
+}
+
+// RecalculateOrphanSizes discards cached sizes and recalculates them in the background
+func (s *SambaService) RecalculateOrphanSizes() error {
+	orphanedDirs, err := s.ListOrphanedDirectories()
+	if err != nil {
+		return err
+	}
+
+	dirPaths := make([]string, 0, len(orphanedDirs))
+	for _, dir := range orphanedDirs {
+		dirPaths = append(dirPaths, dir.Path)
+	}
+	s.orphanSizes.Recalculate(dirPaths)
+
+	return nil
+}
+
+// directorySize returns the total size of all files below a directory
+func directorySize(dirPath string) int64 {
+	var size int64
+	err := filepath.Walk(dirPath, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Skip files/dirs that can't be accessed
+			return nil
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	// If walk fails, report size 0
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// orphanedDirectoryPath validates that a home directory exists and has no Samba user, returning its path
+func (s *SambaService) orphanedDirectoryPath(dirName string) (string, error) {
+	// Directory names are usernames, anything else could escape the home directory
+	if !isValidUsername(dirName) {
+		return "", fmt.Errorf("invalid directory name")
+	}
+
 	// Ensure the directory is actually orphaned
 	users, err := s.ListUsers()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	for _, user := range users {
 		if user.Username == dirName {
-			return fmt.Errorf("cannot delete directory: user '%s' still exists", dirName)
+			return "", fmt.Errorf("directory belongs to existing user '%s'", dirName)
 		}
 	}
 
-	// Delete the directory
 	dirPath := filepath.Join(config.AppConfig.HomeDir, dirName)
+	info, err := os.Stat(dirPath)
+	if os.IsNotExist(err) {
+		return "", utils.NewNotFoundError("Orphaned directory not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat directory: %v", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("'%s' is not a directory", dirName)
+	}
+
+	return dirPath, nil
+}
+
+// DeleteOrphanedDirectory deletes an orphaned home directory
+func (s *SambaService) DeleteOrphanedDirectory(dirName string) error {
+	dirPath, err := s.orphanedDirectoryPath(dirName)
+	if err != nil {
+		return fmt.Errorf("cannot delete directory: %w", err)
+	}
+
+	// Delete the directory
 	if err := os.RemoveAll(dirPath); err != nil {
 		return fmt.Errorf("failed to delete directory: %v", err)
 	}
+	s.orphanSizes.Invalidate(dirPath)
 
 	return nil
 }
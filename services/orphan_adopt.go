@@ -0,0 +1,233 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// AdoptOrphanedDirectory gives an orphaned home directory an owner again, either by recreating
+// the matching user on top of it or by merging its contents into another user's home
+func (s *SambaService) AdoptOrphanedDirectory(dirName string, req *types.AdoptOrphanRequest) (*types.AdoptOrphanResult, error) {
+	dirPath, err := s.orphanedDirectoryPath(dirName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot adopt directory: %w", err)
+	}
+	// Whatever happens the directory is no longer (entirely) the same orphan
+	defer s.orphanSizes.Invalidate(dirPath)
+
+	switch req.Mode {
+	case types.AdoptModeRecreate:
+		return s.recreateOrphanUser(dirName, dirPath, req.Password)
+	case types.AdoptModeMerge:
+		return s.mergeOrphanedDirectory(dirPath, req)
+	}
+
+	return nil, fmt.Errorf("invalid adopt mode: %s", req.Mode)
+}
+
+// recreateOrphanUser recreates the user an orphaned directory belonged to, keeping its data
+func (s *SambaService) recreateOrphanUser(username string, dirPath string, password string) (*types.AdoptOrphanResult, error) {
+	if password == "" {
+		return nil, fmt.Errorf("password is required to recreate the user")
+	}
+
+	s.mu.Lock()
+	err := s.createUserInternal(&types.User{Username: username, Password: password}, true)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.AdoptOrphanResult{
+		Username: username,
+		Path:     dirPath,
+	}, nil
+}
+
+// mergeOrphanedDirectory moves an orphaned directory's contents into a folder of an existing user's home
+func (s *SambaService) mergeOrphanedDirectory(dirPath string, req *types.AdoptOrphanRequest) (*types.AdoptOrphanResult, error) {
+	if !isValidUsername(req.TargetUser) {
+		return nil, fmt.Errorf("invalid target username")
+	}
+	if !s.UserExists(req.TargetUser) {
+		return nil, utils.NewNotFoundError("Target user not found")
+	}
+
+	subFolder, err := cleanSubPath(req.SubFolder)
+	if err != nil {
+		return nil, err
+	}
+	if subFolder == "" {
+		subFolder = filepath.Base(dirPath)
+	}
+
+	onConflict := req.OnConflict
+	if onConflict == "" {
+		onConflict = types.AdoptConflictFail
+	}
+
+	// Confine the target folder to the target home, even if part of it is a symlink
+	targetHome := &BrowseRoot{Path: filepath.Join(config.AppConfig.HomeDir, req.TargetUser)}
+	targetDir, err := NewFileService().ResolvePath(targetHome, subFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check all conflicts up front so "fail" never leaves a half-merged directory
+	if onConflict == types.AdoptConflictFail {
+		conflicts, err := findMergeConflicts(dirPath, targetDir, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(conflicts) > 0 {
+			shown := conflicts
+			if len(shown) > 10 {
+				shown = shown[:10]
+			}
+			return nil, fmt.Errorf("%d path(s) already exist in the target folder: %s", len(conflicts), strings.Join(shown, ", "))
+		}
+	}
+
+	if err := os.MkdirAll(targetDir, 0770); err != nil {
+		return nil, fmt.Errorf("failed to create target folder: %v", err)
+	}
+
+	result := &types.AdoptOrphanResult{
+		Username: req.TargetUser,
+		Path:     targetDir,
+	}
+	if err := mergeDirectory(dirPath, targetDir, "", onConflict, result); err != nil {
+		return result, err
+	}
+
+	// Merged files follow the same ownership model as the rest of the home
+	cmd := exec.Command("chown", "-R", "root:root", targetDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return result, fmt.Errorf("failed to set directory ownership: %v, output: %s", err, output)
+	}
+	cmd = exec.Command("chmod", "-R", "770", targetDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return result, fmt.Errorf("failed to set directory permissions: %v, output: %s", err, output)
+	}
+
+	// Remove the orphaned directory once everything has been moved out of it
+	if len(result.Skipped) == 0 {
+		if err := os.RemoveAll(dirPath); err != nil {
+			return result, fmt.Errorf("files merged but failed to remove orphaned directory: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// findMergeConflicts lists files in src whose path already exists as a file in dst
+// Directories existing on both sides are not conflicts, their contents are merged
+func findMergeConflicts(src string, dst string, rel string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(src, rel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	var conflicts []string
+	for _, entry := range entries {
+		entryRel := filepath.Join(rel, entry.Name())
+		dstInfo, err := os.Lstat(filepath.Join(dst, entryRel))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat target: %v", err)
+		}
+
+		if entry.IsDir() && dstInfo.IsDir() {
+			nested, err := findMergeConflicts(src, dst, entryRel)
+			if err != nil {
+				return nil, err
+			}
+			conflicts = append(conflicts, nested...)
+			continue
+		}
+		conflicts = append(conflicts, filepath.ToSlash(entryRel))
+	}
+
+	return conflicts, nil
+}
+
+// mergeDirectory moves the contents of src/rel into dst/rel, resolving conflicts per strategy
+// Whole directories are moved with a single rename when they don't exist in dst yet
+func mergeDirectory(src string, dst string, rel string, onConflict string, result *types.AdoptOrphanResult) error {
+	entries, err := os.ReadDir(filepath.Join(src, rel))
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		entryRel := filepath.Join(rel, entry.Name())
+		srcPath := filepath.Join(src, entryRel)
+		dstPath := filepath.Join(dst, entryRel)
+
+		dstInfo, err := os.Lstat(dstPath)
+		if os.IsNotExist(err) {
+			if err := os.Rename(srcPath, dstPath); err != nil {
+				return fmt.Errorf("failed to move %s: %v", entryRel, err)
+			}
+			result.Moved++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat target: %v", err)
+		}
+
+		// Both sides are directories: merge recursively
+		if entry.IsDir() && dstInfo.IsDir() {
+			if err := mergeDirectory(src, dst, entryRel, onConflict, result); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch onConflict {
+		case types.AdoptConflictSkip:
+			result.Skipped = append(result.Skipped, filepath.ToSlash(entryRel))
+		case types.AdoptConflictRename:
+			newPath := availablePath(dstPath)
+			if err := os.Rename(srcPath, newPath); err != nil {
+				return fmt.Errorf("failed to move %s: %v", entryRel, err)
+			}
+			newRel, _ := filepath.Rel(dst, newPath)
+			result.Renamed = append(result.Renamed, filepath.ToSlash(newRel))
+			result.Moved++
+		case types.AdoptConflictOverwrite:
+			if err := os.RemoveAll(dstPath); err != nil {
+				return fmt.Errorf("failed to replace %s: %v", entryRel, err)
+			}
+			if err := os.Rename(srcPath, dstPath); err != nil {
+				return fmt.Errorf("failed to move %s: %v", entryRel, err)
+			}
+			result.Moved++
+		default:
+			return fmt.Errorf("path already exists in the target folder: %s", filepath.ToSlash(entryRel))
+		}
+	}
+
+	return nil
+}
+
+// availablePath returns a non-existing variant of a path ("report.pdf" -> "report (1).pdf")
+func availablePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
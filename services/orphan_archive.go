@@ -0,0 +1,424 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	orphanArchivesFileName = "orphan_archives.json"
+
+	orphanArchiveTarGz  = "tar.gz"
+	orphanArchiveTarZst = "tar.zst"
+)
+
+// orphanArchiveMu guards the orphan archive metadata file
+var orphanArchiveMu sync.Mutex
+
+// loadOrphanArchives reads archive metadata (caller must hold orphanArchiveMu)
+func loadOrphanArchives() ([]types.OrphanArchive, error) {
+	archives := []types.OrphanArchive{}
+	if err := readJSONFile(dataFilePath(orphanArchivesFileName), &archives); err != nil {
+		return nil, err
+	}
+	return archives, nil
+}
+
+// saveOrphanArchives writes archive metadata (caller must hold orphanArchiveMu)
+func saveOrphanArchives(archives []types.OrphanArchive) error {
+	return writeJSONFile(dataFilePath(orphanArchivesFileName), archives)
+}
+
+// orphanArchiveFormat returns the archive format for the configured compression
+func orphanArchiveFormat() (string, error) {
+	switch config.AppConfig.OrphanArchive.Compression {
+	case "gzip":
+		return orphanArchiveTarGz, nil
+	case "zstd":
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return "", fmt.Errorf("zstd compression configured but the zstd command is not installed")
+		}
+		return orphanArchiveTarZst, nil
+	}
+	return "", fmt.Errorf("unsupported orphan archive compression: %s", config.AppConfig.OrphanArchive.Compression)
+}
+
+// writeOrphanArchive writes a compressed tar of a directory to w
+func writeOrphanArchive(w io.Writer, dirPath string, format string) error {
+	if format == orphanArchiveTarGz {
+		return writeTarGzArchive(context.Background(), w, dirPath, ArchiveLimits{})
+	}
+
+	// zstd is not in the standard library, compress through the zstd command
+	cmd := exec.Command("zstd", "-q", "-c", "-T0")
+	cmd.Stdout = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe for zstd: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start zstd: %v", err)
+	}
+
+	writeErr := writeTarArchive(context.Background(), stdin, dirPath, ArchiveLimits{})
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("zstd failed: %v", err)
+	}
+	return writeErr
+}
+
+// openOrphanArchive opens an archive file as an uncompressed tar stream
+func openOrphanArchive(archivePath string, format string) (io.ReadCloser, error) {
+	if format == orphanArchiveTarZst {
+		cmd := exec.Command("zstd", "-q", "-d", "-c", archivePath)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout pipe for zstd: %v", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start zstd: %v", err)
+		}
+		return &commandReader{ReadCloser: stdout, cmd: cmd}, nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %v", err)
+	}
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read archive: %v", err)
+	}
+	return &gzipFileReader{Reader: gzipReader, file: file}, nil
+}
+
+// commandReader reads a command's stdout and waits for it on close
+type commandReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *commandReader) Close() error {
+	r.ReadCloser.Close()
+	return r.cmd.Wait()
+}
+
+// gzipFileReader closes both the gzip stream and the underlying file
+type gzipFileReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipFileReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
+
+// ArchiveOrphanedDirectory compresses an orphaned home directory into the archive directory
+// The directory is removed only after the archive has been written completely
+func (s *SambaService) ArchiveOrphanedDirectory(dirName string) (*types.OrphanArchive, error) {
+	dirPath, err := s.orphanedDirectoryPath(dirName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot archive directory: %w", err)
+	}
+
+	format, err := orphanArchiveFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	archiveDir := config.AppConfig.OrphanArchive.Dir
+	if err := os.MkdirAll(archiveDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	createdAt := time.Now()
+	archive := types.OrphanArchive{
+		ID:           fmt.Sprintf("%s-%s", dirName, createdAt.Format("20060102150405")),
+		OriginalName: dirName,
+		Format:       format,
+		Size:         directorySize(dirPath),
+		CreatedAt:    createdAt,
+	}
+	archive.FileName = archive.ID + "." + format
+	archivePath := filepath.Join(archiveDir, archive.FileName)
+
+	// Write to a partial file first so an interrupted run never looks like a valid archive
+	partialPath := archivePath + ".partial"
+	file, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %v", err)
+	}
+	if err := writeOrphanArchive(file, dirPath, format); err != nil {
+		file.Close()
+		_ = os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to archive directory: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		_ = os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to write archive: %v", err)
+	}
+	file.Close()
+
+	if err := os.Rename(partialPath, archivePath); err != nil {
+		_ = os.Remove(partialPath)
+		return nil, fmt.Errorf("failed to finalize archive: %v", err)
+	}
+	if info, err := os.Stat(archivePath); err == nil {
+		archive.ArchiveSize = info.Size()
+	}
+
+	orphanArchiveMu.Lock()
+	archives, err := loadOrphanArchives()
+	if err == nil {
+		archives = append(archives, archive)
+		err = saveOrphanArchives(archives)
+	}
+	orphanArchiveMu.Unlock()
+	if err != nil {
+		_ = os.Remove(archivePath)
+		return nil, err
+	}
+
+	if err := os.RemoveAll(dirPath); err != nil {
+		return nil, fmt.Errorf("archive created but failed to delete directory: %v", err)
+	}
+	s.orphanSizes.Invalidate(dirPath)
+
+	return &archive, nil
+}
+
+// ListOrphanArchives lists archived orphaned directories, newest first
+func (s *SambaService) ListOrphanArchives() ([]types.OrphanArchive, error) {
+	orphanArchiveMu.Lock()
+	defer orphanArchiveMu.Unlock()
+
+	archives, err := loadOrphanArchives()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].CreatedAt.After(archives[j].CreatedAt)
+	})
+
+	return archives, nil
+}
+
+// GetOrphanArchive returns an archive's metadata and the absolute path of its file
+func (s *SambaService) GetOrphanArchive(archiveID string) (*types.OrphanArchive, string, error) {
+	orphanArchiveMu.Lock()
+	defer orphanArchiveMu.Unlock()
+
+	archives, err := loadOrphanArchives()
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, archive := range archives {
+		if archive.ID == archiveID {
+			return &archive, filepath.Join(config.AppConfig.OrphanArchive.Dir, archive.FileName), nil
+		}
+	}
+
+	return nil, "", utils.NewNotFoundError("Archive not found")
+}
+
+// DeleteOrphanArchive permanently deletes an archive and its metadata
+func (s *SambaService) DeleteOrphanArchive(archiveID string) error {
+	orphanArchiveMu.Lock()
+	defer orphanArchiveMu.Unlock()
+
+	archives, err := loadOrphanArchives()
+	if err != nil {
+		return err
+	}
+
+	for i, archive := range archives {
+		if archive.ID != archiveID {
+			continue
+		}
+
+		archivePath := filepath.Join(config.AppConfig.OrphanArchive.Dir, archive.FileName)
+		if err := os.Remove(archivePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete archive: %v", err)
+		}
+
+		archives = append(archives[:i], archives[i+1:]...)
+		return saveOrphanArchives(archives)
+	}
+
+	return utils.NewNotFoundError("Archive not found")
+}
+
+// RestoreOrphanArchive extracts an archive into a new or existing user's home directory
+func (s *SambaService) RestoreOrphanArchive(archiveID string, req *types.RestoreOrphanArchiveRequest) error {
+	archive, archivePath, err := s.GetOrphanArchive(archiveID)
+	if err != nil {
+		return err
+	}
+
+	if !isValidUsername(req.Username) {
+		return fmt.Errorf("invalid username: must contain only letters, numbers, underscore, and dash")
+	}
+
+	subFolder, err := cleanSubPath(req.SubFolder)
+	if err != nil {
+		return err
+	}
+
+	homeDir := filepath.Join(config.AppConfig.HomeDir, req.Username)
+	createdTarget := false
+
+	// Undo a partial restore, including the user created for it
+	rollback := func(err error) error {
+		if createdTarget {
+			_ = os.RemoveAll(filepath.Join(homeDir, subFolder))
+		}
+		if req.CreateUser {
+			if deleteErr := s.DeleteUser(req.Username, true); deleteErr != nil {
+				log.Printf("Failed to delete user %s after a failed restore: %v", req.Username, deleteErr)
+			}
+		}
+		return err
+	}
+
+	if req.CreateUser {
+		if req.Password == "" {
+			return fmt.Errorf("password is required to create the user")
+		}
+		if s.UserExists(req.Username) {
+			return fmt.Errorf("user '%s' already exists", req.Username)
+		}
+		if err := s.CreateUser(&types.User{Username: req.Username, Password: req.Password}); err != nil {
+			return err
+		}
+	} else {
+		if !s.UserExists(req.Username) {
+			return utils.NewNotFoundError("User not found")
+		}
+		// Never mix restored files into the root of an existing home
+		if subFolder == "" {
+			subFolder = archive.OriginalName + "-restored"
+		}
+	}
+
+	targetDir := filepath.Join(homeDir, subFolder)
+	if subFolder != "" {
+		if entries, err := os.ReadDir(targetDir); err == nil && len(entries) > 0 {
+			return rollback(fmt.Errorf("target folder '%s' already exists and is not empty", subFolder))
+		} else if os.IsNotExist(err) {
+			createdTarget = true
+		}
+	}
+
+	if err := extractOrphanArchive(archivePath, archive.Format, targetDir); err != nil {
+		return rollback(err)
+	}
+
+	// Restored files follow the same ownership model as freshly created homes
+	cmd := exec.Command("chown", "-R", "root:root", targetDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return rollback(fmt.Errorf("failed to set directory ownership: %v, output: %s", err, output))
+	}
+	if err := setRestoredPermissions(targetDir); err != nil {
+		return rollback(err)
+	}
+
+	return nil
+}
+
+// setRestoredPermissions makes restored directories 770 and files 660, so no file becomes executable
+func setRestoredPermissions(targetDir string) error {
+	return filepath.WalkDir(targetDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0660)
+		if entry.IsDir() {
+			mode = 0770
+		} else if !entry.Type().IsRegular() {
+			return nil
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("failed to set permissions: %v", err)
+		}
+		return nil
+	})
+}
+
+// extractOrphanArchive extracts an orphan archive into targetDir
+// The top-level folder (the original directory name) is stripped; only regular files and
+// directories are extracted and every path is confined to targetDir
+func extractOrphanArchive(archivePath string, format string, targetDir string) error {
+	reader, err := openOrphanArchive(archivePath, format)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(targetDir, 0770); err != nil {
+		return fmt.Errorf("failed to create target folder: %v", err)
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %v", err)
+		}
+
+		// Strip the top-level folder
+		_, name, _ := strings.Cut(strings.TrimPrefix(header.Name, "/"), "/")
+		relPath, err := cleanSubPath(name)
+		if err != nil || relPath == "" {
+			continue
+		}
+		destPath := filepath.Join(targetDir, relPath)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(destPath, 0770); err != nil {
+				return fmt.Errorf("failed to create directory: %v", err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(destPath), 0770); err != nil {
+				return fmt.Errorf("failed to create directory: %v", err)
+			}
+			file, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0660)
+			if err != nil {
+				return fmt.Errorf("failed to create file: %v", err)
+			}
+			if _, err := io.Copy(file, tarReader); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to extract file: %v", err)
+			}
+			file.Close()
+			_ = os.Chtimes(destPath, header.ModTime, header.ModTime)
+		}
+	}
+
+	return nil
+}
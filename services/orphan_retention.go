@@ -0,0 +1,396 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/queue"
+	"github.com/itsHenry35/SambaManager/types"
+)
+
+const (
+	orphanFirstSeenFileName       = "orphan_first_seen.json"
+	orphanRetentionPolicyFileName = "orphan_retention_policy.json"
+	orphanRetentionStateFileName  = "orphan_retention_state.json"
+
+	// defaultRetentionIntervalHours is used when the policy doesn't set an interval
+	defaultRetentionIntervalHours = 24
+
+	// retentionCheckInterval is how often the scheduler checks whether a run is due
+	retentionCheckInterval = time.Minute
+)
+
+// orphanFirstSeenMu guards the orphan first-seen timestamps file
+var orphanFirstSeenMu sync.Mutex
+
+// trackOrphansFirstSeen records when each orphaned directory was first detected
+// New orphans get the current time, directories that are no longer orphaned are forgotten
+func trackOrphansFirstSeen(dirNames []string) (map[string]time.Time, error) {
+	orphanFirstSeenMu.Lock()
+	defer orphanFirstSeenMu.Unlock()
+
+	stored := make(map[string]time.Time)
+	if err := readJSONFile(dataFilePath(orphanFirstSeenFileName), &stored); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	firstSeen := make(map[string]time.Time, len(dirNames))
+	for _, dirName := range dirNames {
+		if seenAt, found := stored[dirName]; found {
+			firstSeen[dirName] = seenAt
+		} else {
+			firstSeen[dirName] = now
+		}
+	}
+
+	// Only write when something changed, listing orphans is frequent
+	if len(firstSeen) != len(stored) || !sameKeys(firstSeen, stored) {
+		if err := writeJSONFile(dataFilePath(orphanFirstSeenFileName), firstSeen); err != nil {
+			return nil, err
+		}
+	}
+
+	return firstSeen, nil
+}
+
+// markOrphanFirstSeen records a directory as orphaned now (e.g. its user was deleted)
+func markOrphanFirstSeen(dirName string) error {
+	orphanFirstSeenMu.Lock()
+	defer orphanFirstSeenMu.Unlock()
+
+	stored := make(map[string]time.Time)
+	if err := readJSONFile(dataFilePath(orphanFirstSeenFileName), &stored); err != nil {
+		return err
+	}
+	stored[dirName] = time.Now()
+
+	return writeJSONFile(dataFilePath(orphanFirstSeenFileName), stored)
+}
+
+// sameKeys reports whether every key of a also exists in b
+func sameKeys(a map[string]time.Time, b map[string]time.Time) bool {
+	for key := range a {
+		if _, found := b[key]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// orphanRetentionState is the persisted scheduler state
+type orphanRetentionState struct {
+	LastRunAt *time.Time                `json:"last_run_at,omitempty"`
+	LastRun   *types.OrphanRetentionRun `json:"last_run,omitempty"`
+}
+
+// OrphanRetentionService enforces the orphan retention policy on a schedule
+type OrphanRetentionService struct {
+	mu            sync.Mutex // Guards the policy and state files
+	runMu         sync.Mutex // Allows only one run at a time
+	samba         *SambaService
+	notifications *NotificationService
+	queue         *queue.Queue
+	stop          chan struct{}
+}
+
+// NewOrphanRetentionService creates a new orphan retention service
+// Actions are submitted to the queue so they never race with admin operations
+func NewOrphanRetentionService(samba *SambaService, notifications *NotificationService, q *queue.Queue) *OrphanRetentionService {
+	return &OrphanRetentionService{
+		samba:         samba,
+		notifications: notifications,
+		queue:         q,
+		stop:          make(chan struct{}),
+	}
+}
+
+// GetPolicy returns the current retention policy (disabled by default)
+func (s *OrphanRetentionService) GetPolicy() (*types.OrphanRetentionPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.getPolicyInternal()
+}
+
+// getPolicyInternal reads the policy without acquiring lock
+func (s *OrphanRetentionService) getPolicyInternal() (*types.OrphanRetentionPolicy, error) {
+	policy := &types.OrphanRetentionPolicy{
+		ArchiveAfterDays:        30,
+		DeleteArchivesAfterDays: 180,
+		IntervalHours:           defaultRetentionIntervalHours,
+	}
+	if err := readJSONFile(dataFilePath(orphanRetentionPolicyFileName), policy); err != nil {
+		return nil, err
+	}
+	if policy.IntervalHours <= 0 {
+		policy.IntervalHours = defaultRetentionIntervalHours
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces the retention policy
+func (s *OrphanRetentionService) UpdatePolicy(policy *types.OrphanRetentionPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy.IntervalHours <= 0 {
+		policy.IntervalHours = defaultRetentionIntervalHours
+	}
+	return writeJSONFile(dataFilePath(orphanRetentionPolicyFileName), policy)
+}
+
+// getStateInternal reads the scheduler state without acquiring lock
+func (s *OrphanRetentionService) getStateInternal() (*orphanRetentionState, error) {
+	state := &orphanRetentionState{}
+	if err := readJSONFile(dataFilePath(orphanRetentionStateFileName), state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// nextRunAt returns when the next scheduled run is due (nil when disabled)
+// A policy that has never run is due immediately
+func nextRunAt(policy *types.OrphanRetentionPolicy, state *orphanRetentionState) *time.Time {
+	if !policy.Enabled {
+		return nil
+	}
+	next := time.Now()
+	if state.LastRunAt != nil {
+		next = state.LastRunAt.Add(time.Duration(policy.IntervalHours) * time.Hour)
+	}
+	return &next
+}
+
+// GetLastRun returns the result of the most recent run (nil if it never ran)
+func (s *OrphanRetentionService) GetLastRun() (*types.OrphanRetentionRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.getStateInternal()
+	if err != nil {
+		return nil, err
+	}
+	return state.LastRun, nil
+}
+
+// Preview returns the actions the next run will take, evaluated at the time it is due
+func (s *OrphanRetentionService) Preview() (*types.OrphanRetentionPreview, error) {
+	s.mu.Lock()
+	policy, err := s.getPolicyInternal()
+	var state *orphanRetentionState
+	if err == nil {
+		state, err = s.getStateInternal()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &types.OrphanRetentionPreview{
+		Enabled:   policy.Enabled,
+		NextRunAt: nextRunAt(policy, state),
+	}
+
+	at := time.Now()
+	if preview.NextRunAt != nil && preview.NextRunAt.After(at) {
+		at = *preview.NextRunAt
+	}
+	preview.Actions, err = s.planActions(policy, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+// planActions lists the actions the policy requires at a given time
+func (s *OrphanRetentionService) planActions(policy *types.OrphanRetentionPolicy, at time.Time) ([]types.OrphanRetentionAction, error) {
+	actions := []types.OrphanRetentionAction{}
+
+	if policy.ArchiveAfterDays > 0 {
+		orphanedDirs, err := s.samba.ListOrphanedDirectories()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list orphaned directories: %v", err)
+		}
+		archiveAfter := time.Duration(policy.ArchiveAfterDays) * 24 * time.Hour
+		for _, dir := range orphanedDirs {
+			if dir.FirstSeenAt == nil || at.Sub(*dir.FirstSeenAt) < archiveAfter {
+				continue
+			}
+			actions = append(actions, types.OrphanRetentionAction{
+				Type:   types.RetentionActionArchive,
+				Target: dir.Name,
+				Since:  *dir.FirstSeenAt,
+				Size:   dir.Size,
+			})
+		}
+	}
+
+	if policy.DeleteArchivesAfterDays > 0 {
+		archives, err := s.samba.ListOrphanArchives()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list orphan archives: %v", err)
+		}
+		deleteAfter := time.Duration(policy.DeleteArchivesAfterDays) * 24 * time.Hour
+		for _, archive := range archives {
+			if at.Sub(archive.CreatedAt) < deleteAfter {
+				continue
+			}
+			actions = append(actions, types.OrphanRetentionAction{
+				Type:   types.RetentionActionDeleteArchive,
+				Target: archive.ID,
+				Since:  archive.CreatedAt,
+				Size:   archive.ArchiveSize,
+			})
+		}
+	}
+
+	return actions, nil
+}
+
+// Run applies the retention policy now and notifies administrators of the actions taken
+// It must not be called from inside a queue task, as every action is submitted to the queue
+func (s *OrphanRetentionService) Run(trigger string) (*types.OrphanRetentionRun, error) {
+	if !s.runMu.TryLock() {
+		return nil, fmt.Errorf("a retention run is already in progress")
+	}
+	defer s.runMu.Unlock()
+
+	policy, err := s.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &types.OrphanRetentionRun{
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+	}
+	run.Actions, err = s.planActions(policy, run.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range run.Actions {
+		action := &run.Actions[i]
+
+		// Stop early when the server shuts down, the remaining actions are retried next run
+		select {
+		case <-s.stop:
+			action.Error = "skipped: server shutting down"
+			run.Failed++
+			continue
+		default:
+		}
+
+		err := s.queue.SubmitSync(func() error {
+			if action.Type == types.RetentionActionArchive {
+				_, err := s.samba.ArchiveOrphanedDirectory(action.Target)
+				return err
+			}
+			return s.samba.DeleteOrphanArchive(action.Target)
+		})
+		if err != nil {
+			action.Error = err.Error()
+			run.Failed++
+		}
+	}
+	run.FinishedAt = time.Now()
+
+	s.mu.Lock()
+	err = writeJSONFile(dataFilePath(orphanRetentionStateFileName), &orphanRetentionState{
+		LastRunAt: &run.StartedAt,
+		LastRun:   run,
+	})
+	s.mu.Unlock()
+	if err != nil {
+		return run, err
+	}
+
+	if len(run.Actions) > 0 {
+		if err := s.notifications.Notify("Orphan retention", summarizeRetentionRun(run)); err != nil {
+			log.Printf("Failed to record retention notification: %v", err)
+		}
+	}
+
+	return run, nil
+}
+
+// summarizeRetentionRun describes the actions of a run in one sentence
+func summarizeRetentionRun(run *types.OrphanRetentionRun) string {
+	var archived, deleted []string
+	for _, action := range run.Actions {
+		if action.Error != "" {
+			continue
+		}
+		if action.Type == types.RetentionActionArchive {
+			archived = append(archived, action.Target)
+		} else {
+			deleted = append(deleted, action.Target)
+		}
+	}
+
+	var parts []string
+	if len(archived) > 0 {
+		parts = append(parts, fmt.Sprintf("archived orphaned directories (%d): %s", len(archived), strings.Join(archived, ", ")))
+	}
+	if len(deleted) > 0 {
+		parts = append(parts, fmt.Sprintf("deleted expired archives (%d): %s", len(deleted), strings.Join(deleted, ", ")))
+	}
+	if run.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("failed actions: %d", run.Failed))
+	}
+
+	return fmt.Sprintf("Run (%s): %s", run.Trigger, strings.Join(parts, "; "))
+}
+
+// Start runs the scheduler in the background until Stop is called
+func (s *OrphanRetentionService) Start() {
+	go func() {
+		ticker := time.NewTicker(retentionCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if s.isDue() {
+					if _, err := s.Run("scheduled"); err != nil {
+						log.Printf("Orphan retention run failed: %v", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler and waits for a running run to finish
+func (s *OrphanRetentionService) Stop() {
+	close(s.stop)
+	s.runMu.Lock()
+	s.runMu.Unlock()
+}
+
+// isDue reports whether a scheduled run should start now
+func (s *OrphanRetentionService) isDue() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy, err := s.getPolicyInternal()
+	if err != nil {
+		log.Printf("Failed to read orphan retention policy: %v", err)
+		return false
+	}
+	state, err := s.getStateInternal()
+	if err != nil {
+		log.Printf("Failed to read orphan retention state: %v", err)
+		return false
+	}
+
+	next := nextRunAt(policy, state)
+	return next != nil && !next.After(time.Now())
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+)
+
+const (
+	orphanSizesFileName = "orphan_sizes.json"
+
+	// orphanSizeMaxAge forces a recalculation even if the directory mtime is unchanged,
+	// as the mtime only reflects changes directly inside the directory
+	orphanSizeMaxAge = 24 * time.Hour
+
+	// orphanSizeProgressInterval is how many files are scanned between progress updates
+	orphanSizeProgressInterval = 1000
+)
+
+// orphanSizeEntry is a cached size calculation result
+type orphanSizeEntry struct {
+	Size         int64     `json:"size"`
+	FileCount    int64     `json:"file_count"`
+	DirModTime   time.Time `json:"dir_mod_time"` // Directory mtime the result was calculated for
+	CalculatedAt time.Time `json:"calculated_at"`
+}
+
+// orphanSizeCalculator computes orphaned directory sizes in the background and caches them
+type orphanSizeCalculator struct {
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]orphanSizeEntry // Keyed by directory path
+	pending []string
+	queued  map[string]bool
+
+	current      string
+	currentFiles int64
+	currentBytes int64
+
+	wake      chan struct{}
+	startOnce sync.Once
+}
+
+func newOrphanSizeCalculator() *orphanSizeCalculator {
+	return &orphanSizeCalculator{
+		entries: make(map[string]orphanSizeEntry),
+		queued:  make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// load reads the persisted cache on first use (must hold lock)
+func (c *orphanSizeCalculator) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	if err := readJSONFile(dataFilePath(orphanSizesFileName), &c.entries); err != nil {
+		log.Printf("Failed to load orphan size cache: %v", err)
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]orphanSizeEntry)
+	}
+}
+
+// save persists the cache (must hold lock)
+func (c *orphanSizeCalculator) save() {
+	if err := writeJSONFile(dataFilePath(orphanSizesFileName), c.entries); err != nil {
+		log.Printf("Failed to save orphan size cache: %v", err)
+	}
+}
+
+// Lookup fills in the size fields of an orphaned directory from the cache
+// Missing or stale entries are queued for calculation and reported as calculating
+func (c *orphanSizeCalculator) Lookup(dir *types.OrphanedDirectory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	entry, found := c.entries[dir.Path]
+	if found {
+		dir.Size = entry.Size
+		dir.FileCount = entry.FileCount
+		calculatedAt := entry.CalculatedAt
+		dir.CalculatedAt = &calculatedAt
+	}
+
+	if found && entry.DirModTime.Equal(dir.LastModified) && time.Since(entry.CalculatedAt) < orphanSizeMaxAge {
+		dir.SizeStatus = types.SizeStatusReady
+		return
+	}
+
+	dir.SizeStatus = types.SizeStatusCalculating
+	if c.current == dir.Path {
+		// Report partial progress for the directory being scanned
+		dir.Size = c.currentBytes
+		dir.FileCount = c.currentFiles
+	}
+	c.enqueue(dir.Path)
+}
+
+// Recalculate drops cached results and queues the directories again
+func (c *orphanSizeCalculator) Recalculate(dirPaths []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	for _, dirPath := range dirPaths {
+		delete(c.entries, dirPath)
+		c.enqueue(dirPath)
+	}
+	c.save()
+}
+
+// Invalidate forgets a directory (after it was deleted, archived or adopted)
+func (c *orphanSizeCalculator) Invalidate(dirPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+
+	if _, found := c.entries[dirPath]; found {
+		delete(c.entries, dirPath)
+		c.save()
+	}
+}
+
+// Status returns the progress of the background calculation
+func (c *orphanSizeCalculator) Status() types.OrphanSizeJobStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return types.OrphanSizeJobStatus{
+		Running:      c.current != "",
+		Current:      filepath.Base(c.current),
+		CurrentFiles: c.currentFiles,
+		CurrentBytes: c.currentBytes,
+		Pending:      len(c.pending),
+	}
+}
+
+// enqueue queues a directory once and wakes the worker (must hold lock)
+func (c *orphanSizeCalculator) enqueue(dirPath string) {
+	if c.queued[dirPath] || c.current == dirPath {
+		return
+	}
+	c.queued[dirPath] = true
+	c.pending = append(c.pending, dirPath)
+
+	c.startOnce.Do(func() {
+		go c.worker()
+	})
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// worker scans queued directories one at a time
+func (c *orphanSizeCalculator) worker() {
+	for {
+		c.mu.Lock()
+		if len(c.pending) == 0 {
+			c.mu.Unlock()
+			<-c.wake
+			continue
+		}
+		dirPath := c.pending[0]
+		c.pending = c.pending[1:]
+		delete(c.queued, dirPath)
+		c.current = dirPath
+		c.currentFiles = 0
+		c.currentBytes = 0
+		c.mu.Unlock()
+
+		entry, err := c.scan(dirPath)
+
+		c.mu.Lock()
+		c.current = ""
+		if err == nil {
+			c.entries[dirPath] = entry
+			c.save()
+		}
+		c.mu.Unlock()
+	}
+}
+
+// scan walks a directory, publishing progress as it goes
+func (c *orphanSizeCalculator) scan(dirPath string) (orphanSizeEntry, error) {
+	entry := orphanSizeEntry{}
+
+	// Record the mtime before walking so changes during the walk invalidate the result
+	dirInfo, err := os.Stat(dirPath)
+	if err != nil {
+		return entry, err
+	}
+	entry.DirModTime = dirInfo.ModTime()
+
+	_ = filepath.WalkDir(dirPath, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			// Skip files/dirs that can't be accessed
+			return nil
+		}
+		fileInfo, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entry.Size += fileInfo.Size()
+		entry.FileCount++
+
+		if entry.FileCount%orphanSizeProgressInterval == 0 {
+			c.mu.Lock()
+			c.currentFiles = entry.FileCount
+			c.currentBytes = entry.Size
+			c.mu.Unlock()
+		}
+		return nil
+	})
+
+	entry.CalculatedAt = time.Now()
+	return entry, nil
+}
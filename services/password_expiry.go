@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// sambaNeverTimestamp is the largest 32-bit time, which pdbedit treats as "never"
+const sambaNeverTimestamp = 2147483647
+
+// SetPasswordExpiry updates whether a user must change their password and its maximum age
+func (s *SambaService) SetPasswordExpiry(username string, req *types.PasswordExpiryRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// SECURITY: validate username format to prevent command injection
+	if !isValidUsername(username) {
+		return fmt.Errorf("invalid username")
+	}
+	if !s.UserExists(username) {
+		return utils.NewNotFoundError("User not found")
+	}
+
+	if err := updateUserMeta(username, func(meta *userMeta) {
+		meta.MustChangePassword = req.MustChangePassword
+		meta.PasswordMaxAgeDays = req.MaxAgeDays
+	}); err != nil {
+		return err
+	}
+
+	return applySambaPasswordExpiry(username)
+}
+
+// RequirePasswordChange makes a user change their password at the next login
+func (s *SambaService) RequirePasswordChange(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// SECURITY: validate username format to prevent command injection
+	if !isValidUsername(username) {
+		return fmt.Errorf("invalid username")
+	}
+	if !s.UserExists(username) {
+		return utils.NewNotFoundError("User not found")
+	}
+
+	if err := updateUserMeta(username, func(meta *userMeta) {
+		meta.MustChangePassword = true
+	}); err != nil {
+		return err
+	}
+
+	return applySambaPasswordExpiry(username)
+}
+
+// clearPasswordChangeInternal resets the forced change and restarts the maximum age after a new password was set (must hold lock)
+func clearPasswordChangeInternal(username string) error {
+	var hasExpiry bool
+	if err := updateUserMeta(username, func(meta *userMeta) {
+		hasExpiry = meta.MustChangePassword || meta.PasswordMaxAgeDays > 0
+		meta.MustChangePassword = false
+	}); err != nil {
+		return err
+	}
+
+	// Users without expiry settings keep Samba's own account policy
+	if !hasExpiry {
+		return nil
+	}
+	return applySambaPasswordExpiry(username)
+}
+
+// applySambaPasswordExpiry mirrors a user's expiry settings into Samba's "password must change" time
+// so SMB logons are refused with NT_STATUS_PASSWORD_MUST_CHANGE as well (must hold lock)
+func applySambaPasswordExpiry(username string) error {
+	account, err := getSambaAccount(username)
+	if err != nil {
+		return utils.NewNotFoundError("User not found")
+	}
+	meta, err := loadUserMeta()
+	if err != nil {
+		return err
+	}
+
+	mustChange := int64(sambaNeverTimestamp)
+	if expiresAt := passwordExpiresAt(account, meta[username]); expiresAt != nil {
+		mustChange = expiresAt.Unix()
+	}
+
+	cmd := exec.Command("pdbedit", "-u", username, "--pwd-must-change-time="+strconv.FormatInt(mustChange, 10))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set password expiry: %v, output: %s", err, output)
+	}
+
+	return nil
+}
+
+// passwordExpiresAt returns when a user's password expires according to their settings, nil if never
+func passwordExpiresAt(account *sambaAccount, meta userMeta) *time.Time {
+	now := time.Now()
+	if meta.MustChangePassword {
+		return &now
+	}
+	if meta.PasswordMaxAgeDays > 0 {
+		// A password without a known change time expires immediately
+		if account.PasswordLastSet == nil {
+			return &now
+		}
+		expiresAt := account.PasswordLastSet.AddDate(0, 0, meta.PasswordMaxAgeDays)
+		return &expiresAt
+	}
+	return nil
+}
+
+// passwordExpired reports whether a user has to change their password before logging in
+// Both the user's own settings and Samba's account policy (e.g. "maximum password age") count
+func passwordExpired(account *sambaAccount, meta userMeta) bool {
+	now := time.Now()
+	if expiresAt := passwordExpiresAt(account, meta); expiresAt != nil && !expiresAt.After(now) {
+		return true
+	}
+	return account.PasswordMustChange != nil && !account.PasswordMustChange.After(now)
+}
+
+// PasswordChangeRequired reports whether a user has to change their password at login
+func PasswordChangeRequired(username string) bool {
+	account, err := getSambaAccount(username)
+	if err != nil {
+		return false
+	}
+	meta, err := loadUserMeta()
+	if err != nil {
+		return false
+	}
+	return passwordExpired(account, meta[username])
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordPolicyFileName  = "password_policy.json"
+	passwordHistoryFileName = "password_history.json"
+)
+
+// passwordPolicyMu guards the password policy and password history files
+var passwordPolicyMu sync.Mutex
+
+// defaultPasswordPolicy is used until an admin saves a policy
+// It keeps the previous 3 character minimum so existing scripts keep working, admins tighten it in the settings
+func defaultPasswordPolicy() *types.PasswordPolicy {
+	return &types.PasswordPolicy{
+		MinLength: 3,
+	}
+}
+
+// GetPasswordPolicy returns the current password policy and whether its dictionary is available
+func (s *SambaService) GetPasswordPolicy() (*types.PasswordPolicyResponse, error) {
+	policy, err := loadPasswordPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PasswordPolicyResponse{
+		PasswordPolicy:      *policy,
+		DictionaryAvailable: passwordDictionaryCache.available(),
+	}, nil
+}
+
+// UpdatePasswordPolicy replaces the password policy
+// Existing passwords are not checked, the policy applies to the next password change
+func (s *SambaService) UpdatePasswordPolicy(policy *types.PasswordPolicy) error {
+	passwordPolicyMu.Lock()
+	defer passwordPolicyMu.Unlock()
+
+	return writeJSONFile(dataFilePath(passwordPolicyFileName), policy)
+}
+
+// loadPasswordPolicy reads the password policy
+func loadPasswordPolicy() (*types.PasswordPolicy, error) {
+	passwordPolicyMu.Lock()
+	defer passwordPolicyMu.Unlock()
+
+	policy := defaultPasswordPolicy()
+	if err := readJSONFile(dataFilePath(passwordPolicyFileName), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// checkPasswordPolicy returns a ValidationError listing every rule a new password breaks
+// With checkHistory the password is also compared with the user's previous passwords
+func checkPasswordPolicy(username string, password string, checkHistory bool) error {
+	policy, err := loadPasswordPolicy()
+	if err != nil {
+		return err
+	}
+
+	problems := passwordPolicyViolations(policy, username, password)
+	if checkHistory && policy.HistorySize > 0 && passwordInHistory(username, password, policy.HistorySize) {
+		problems = append(problems, fmt.Sprintf("password must not be one of the last %d passwords", policy.HistorySize))
+	}
+
+	if len(problems) > 0 {
+		return utils.NewValidationError(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// passwordPolicyViolations returns the rules a password breaks, without the history check
+func passwordPolicyViolations(policy *types.PasswordPolicy, username string, password string) []string {
+	var problems []string
+
+	if len([]rune(password)) < policy.MinLength {
+		problems = append(problems, fmt.Sprintf("password must be at least %d characters", policy.MinLength))
+	}
+	// smbpasswd reads the password from a line of stdin
+	if strings.ContainsAny(password, "\r\n") {
+		problems = append(problems, "password must not contain line breaks")
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUppercase && !hasUpper {
+		problems = append(problems, "password must contain an uppercase letter")
+	}
+	if policy.RequireLowercase && !hasLower {
+		problems = append(problems, "password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		problems = append(problems, "password must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		problems = append(problems, "password must contain a symbol")
+	}
+
+	if policy.RejectUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "password must not contain the username")
+	}
+	if policy.CheckDictionary && passwordDictionaryCache.contains(password) {
+		problems = append(problems, "password is too common")
+	}
+
+	return problems
+}
+
+// passwordInHistory reports whether a password matches one of the user's last passwords
+func passwordInHistory(username string, password string, historySize int) bool {
+	passwordPolicyMu.Lock()
+	defer passwordPolicyMu.Unlock()
+
+	history := make(map[string][]string)
+	if err := readJSONFile(dataFilePath(passwordHistoryFileName), &history); err != nil {
+		log.Printf("Failed to read password history: %v", err)
+		return false
+	}
+
+	hashes := history[username]
+	if len(hashes) > historySize {
+		hashes = hashes[:historySize]
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPasswordHistory remembers a user's new password as a bcrypt hash
+// Only as many passwords as the policy's history size are kept
+func recordPasswordHistory(username string, password string) error {
+	policy, err := loadPasswordPolicy()
+	if err != nil {
+		return err
+	}
+
+	var hash []byte
+	if policy.HistorySize > 0 {
+		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %v", err)
+		}
+	}
+
+	return updatePasswordHistory(func(history map[string][]string) {
+		if policy.HistorySize == 0 {
+			delete(history, username)
+			return
+		}
+		hashes := append([]string{string(hash)}, history[username]...)
+		if len(hashes) > policy.HistorySize {
+			hashes = hashes[:policy.HistorySize]
+		}
+		history[username] = hashes
+	})
+}
+
+// deletePasswordHistory forgets the password history of a deleted user
+func deletePasswordHistory(username string) error {
+	return updatePasswordHistory(func(history map[string][]string) {
+		delete(history, username)
+	})
+}
+
+// renamePasswordHistory moves a user's password history to a new username
+func renamePasswordHistory(oldName string, newName string) error {
+	return updatePasswordHistory(func(history map[string][]string) {
+		if hashes, ok := history[oldName]; ok {
+			history[newName] = hashes
+			delete(history, oldName)
+		}
+	})
+}
+
+// updatePasswordHistory applies fn to the password history file and saves it
+func updatePasswordHistory(fn func(history map[string][]string)) error {
+	passwordPolicyMu.Lock()
+	defer passwordPolicyMu.Unlock()
+
+	history := make(map[string][]string)
+	if err := readJSONFile(dataFilePath(passwordHistoryFileName), &history); err != nil {
+		return err
+	}
+
+	fn(history)
+
+	return writeJSONFile(dataFilePath(passwordHistoryFileName), history)
+}
+
+// passwordDictionary caches the rejected passwords of the dictionary file
+// The file is reloaded when it changes
+type passwordDictionary struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	words   map[string]bool
+}
+
+var passwordDictionaryCache = &passwordDictionary{}
+
+// load (re)reads the dictionary file if needed, returning false if it is unavailable (must hold lock)
+func (d *passwordDictionary) load() bool {
+	path := config.AppConfig.PasswordPolicy.DictionaryFile
+	info, err := os.Stat(path)
+	if err != nil {
+		d.words = nil
+		return false
+	}
+	if d.words != nil && d.path == path && info.ModTime().Equal(d.modTime) {
+		return true
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Failed to open password dictionary: %v", err)
+		d.words = nil
+		return false
+	}
+	defer file.Close()
+
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			words[strings.ToLower(word)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Failed to read password dictionary: %v", err)
+		d.words = nil
+		return false
+	}
+
+	d.path = path
+	d.modTime = info.ModTime()
+	d.words = words
+	return true
+}
+
+// available reports whether the dictionary file can be used
+func (d *passwordDictionary) available() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.load()
+}
+
+// contains reports whether a password is listed in the dictionary (case-insensitive)
+func (d *passwordDictionary) contains(password string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.load() {
+		return false
+	}
+	return d.words[strings.ToLower(password)]
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	passwordResetsFileName = "password_resets.json"
+
+	defaultPasswordResetLifetime = 60 * time.Minute
+)
+
+// PasswordResetService manages one-time tokens that let users set a new password without logging in
+type PasswordResetService struct {
+	mu    sync.Mutex
+	samba *SambaService
+}
+
+// NewPasswordResetService creates a new password reset service
+func NewPasswordResetService(samba *SambaService) *PasswordResetService {
+	return &PasswordResetService{
+		samba: samba,
+	}
+}
+
+// loadResets reads all stored reset tokens, dropping expired ones (must hold lock)
+func (s *PasswordResetService) loadResets() ([]types.PasswordReset, error) {
+	var resets []types.PasswordReset
+	if err := readJSONFile(dataFilePath(passwordResetsFileName), &resets); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]types.PasswordReset, 0, len(resets))
+	for _, reset := range resets {
+		if reset.ExpiresAt.After(now) {
+			active = append(active, reset)
+		}
+	}
+
+	return active, nil
+}
+
+// saveResets writes all reset tokens (must hold lock)
+func (s *PasswordResetService) saveResets(resets []types.PasswordReset) error {
+	return writeJSONFile(dataFilePath(passwordResetsFileName), resets)
+}
+
+// hashResetToken returns the stored form of a reset token
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateReset issues a reset token for a user, replacing any earlier token of that user
+func (s *PasswordResetService) CreateReset(username string, createdBy string, req *types.CreatePasswordResetRequest) (*types.PasswordResetResponse, error) {
+	account, err := getSambaAccount(username)
+	if err != nil {
+		return nil, utils.NewNotFoundError("User not found")
+	}
+	if account.Disabled() {
+		return nil, fmt.Errorf("account is disabled")
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, fmt.Errorf("failed to generate reset token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+
+	lifetime := defaultPasswordResetLifetime
+	if req.LifetimeMinutes > 0 {
+		lifetime = time.Duration(req.LifetimeMinutes) * time.Minute
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resets, err := s.loadResets()
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make([]types.PasswordReset, 0, len(resets)+1)
+	for _, reset := range resets {
+		if reset.Username != username {
+			remaining = append(remaining, reset)
+		}
+	}
+
+	now := time.Now()
+	reset := types.PasswordReset{
+		TokenHash: hashResetToken(token),
+		Username:  username,
+		SID:       account.SID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lifetime),
+	}
+	if err := s.saveResets(append(remaining, reset)); err != nil {
+		return nil, err
+	}
+
+	return &types.PasswordResetResponse{
+		Username:  username,
+		Token:     token,
+		ExpiresAt: reset.ExpiresAt,
+	}, nil
+}
+
+// RevokeResets deletes the reset token of a user
+func (s *PasswordResetService) RevokeResets(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resets, err := s.loadResets()
+	if err != nil {
+		return err
+	}
+
+	remaining := make([]types.PasswordReset, 0, len(resets))
+	for _, reset := range resets {
+		if reset.Username != username {
+			remaining = append(remaining, reset)
+		}
+	}
+
+	return s.saveResets(remaining)
+}
+
+// findReset returns the index of the reset matching a token (must hold lock)
+// Unknown, expired and stale tokens are all reported as not found
+func (s *PasswordResetService) findReset(resets []types.PasswordReset, token string) (int, error) {
+	hash := hashResetToken(token)
+	for i, reset := range resets {
+		if reset.TokenHash != hash {
+			continue
+		}
+
+		// The user may have been deleted, recreated or disabled since the token was issued
+		account, err := getSambaAccount(reset.Username)
+		if err != nil || account.SID != reset.SID || account.Disabled() {
+			break
+		}
+		return i, nil
+	}
+
+	return -1, utils.NewNotFoundError("Invalid or expired reset token")
+}
+
+// GetResetInfo returns the username and password policy for a reset token without using it
+func (s *PasswordResetService) GetResetInfo(token string) (*types.PasswordResetInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resets, err := s.loadResets()
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.findReset(resets, token)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.samba.GetPasswordPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PasswordResetInfo{
+		Username:  resets[index].Username,
+		ExpiresAt: resets[index].ExpiresAt,
+		Policy:    *policy,
+	}, nil
+}
+
+// CompleteReset sets a new password with a reset token and invalidates the token
+// The token stays valid if the new password is rejected, so the user can try again
+func (s *PasswordResetService) CompleteReset(token string, newPassword string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resets, err := s.loadResets()
+	if err != nil {
+		return "", err
+	}
+	index, err := s.findReset(resets, token)
+	if err != nil {
+		return "", err
+	}
+
+	// Remove the token first so it can never be used twice
+	username := resets[index].Username
+	remaining := append(append([]types.PasswordReset{}, resets[:index]...), resets[index+1:]...)
+	if err := s.saveResets(remaining); err != nil {
+		return "", err
+	}
+
+	if err := s.samba.ChangePassword(username, newPassword); err != nil {
+		if saveErr := s.saveResets(resets); saveErr != nil {
+			log.Printf("Failed to restore password reset token of %s: %v", username, saveErr)
+		}
+		return "", err
+	}
+
+	return username, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pdbeditNever is the first time Samba uses to mean "never" (the 32-bit time_t limit)
+var pdbeditNever = time.Date(2036, time.February, 6, 0, 0, 0, 0, time.UTC)
+
+// sambaAccount is a tdbsam account as reported by pdbedit -Lv
+type sambaAccount struct {
+	Username           string
+	FullName           string
+	Description        string
+	SID                string
+	Domain             string
+	Flags              string // Account flags without brackets, e.g. "DU"
+	PasswordLastSet    *time.Time
+	PasswordCanChange  *time.Time
+	PasswordMustChange *time.Time
+	LogonTime          *time.Time
+	LogoffTime         *time.Time
+	KickoffTime        *time.Time
+	LastBadPassword    *time.Time
+	BadPasswordCount   int
+	LogonHours         string
+}
+
+// Disabled reports whether the account is disabled (smbpasswd -d)
+func (a *sambaAccount) Disabled() bool {
+	return strings.Contains(a.Flags, "D")
+}
+
+// Locked reports whether the account was locked out automatically after bad passwords
+func (a *sambaAccount) Locked() bool {
+	return strings.Contains(a.Flags, "L")
+}
+
+// pdbeditCommand builds a pdbedit command printing times in UTC
+// Zone abbreviations such as "CEST" can't be parsed reliably, "UTC" can
+func pdbeditCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("pdbedit", args...)
+	cmd.Env = append(os.Environ(), "TZ=UTC")
+	return cmd
+}
+
+// listSambaAccounts returns all tdbsam accounts with their details
+func listSambaAccounts() ([]sambaAccount, error) {
+	cmd := pdbeditCommand("-L", "-v")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list samba users: %v, output: %s", err, output)
+	}
+
+	return parsePdbeditVerbose(string(output)), nil
+}
+
+// getSambaAccount returns the details of a single tdbsam account
+func getSambaAccount(username string) (*sambaAccount, error) {
+	// SECURITY: validate username format to prevent command injection
+	if !isValidUsername(username) {
+		return nil, fmt.Errorf("invalid username")
+	}
+
+	cmd := pdbeditCommand("-L", "-v", "-u", username)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %s", username)
+	}
+
+	accounts := parsePdbeditVerbose(string(output))
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("user not found: %s", username)
+	}
+	return &accounts[0], nil
+}
+
+// parsePdbeditVerbose parses the "Key: value" blocks printed by pdbedit -Lv
+// Blocks are separated by lines of dashes
+func parsePdbeditVerbose(output string) []sambaAccount {
+	var accounts []sambaAccount
+	fields := make(map[string]string)
+
+	flush := func() {
+		if username := fields["unix username"]; username != "" {
+			accounts = append(accounts, sambaAccountFromFields(fields))
+		}
+		fields = make(map[string]string)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "---") {
+			flush()
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		fields[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	flush()
+
+	return accounts
+}
+
+// sambaAccountFromFields builds an account from the parsed fields of one block
+func sambaAccountFromFields(fields map[string]string) sambaAccount {
+	badPasswordCount, _ := strconv.Atoi(fields["bad password count"])
+
+	return sambaAccount{
+		Username:           fields["unix username"],
+		FullName:           fields["full name"],
+		Description:        fields["account desc"],
+		SID:                fields["user sid"],
+		Domain:             fields["domain"],
+		Flags:              strings.TrimSpace(strings.Trim(fields["account flags"], "[]")),
+		PasswordLastSet:    parsePdbeditTime(fields["password last set"]),
+		PasswordCanChange:  parsePdbeditTime(fields["password can change"]),
+		PasswordMustChange: parsePdbeditTime(fields["password must change"]),
+		LogonTime:          parsePdbeditTime(fields["logon time"]),
+		LogoffTime:         parsePdbeditTime(fields["logoff time"]),
+		KickoffTime:        parsePdbeditTime(fields["kickoff time"]),
+		LastBadPassword:    parsePdbeditTime(fields["last bad password"]),
+		BadPasswordCount:   badPasswordCount,
+		LogonHours:         fields["logon hours"],
+	}
+}
+
+// parsePdbeditTime parses a pdbedit timestamp, returning nil for "0", "never" and unparsable values
+func parsePdbeditTime(value string) *time.Time {
+	if value == "" || value == "0" || value == "never" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC1123, time.RFC1123Z, "Mon, 02 Jan 2006 15:04:05 -0700 MST"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if !t.Before(pdbeditNever) || t.Unix() <= 0 {
+				return nil
+			}
+			return &t
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// pdbeditSample is "pdbedit -L -v" output for an active user and a disabled, locked out user
+const pdbeditSample = `---------------
+Unix username:        alice
+NT username:
+Account Flags:        [U          ]
+User SID:             S-1-5-21-1-2-3-1000
+Primary Group SID:    S-1-5-21-1-2-3-513
+Full Name:            Alice Example
+Home Directory:       \\server\alice
+Account desc:         Accounting
+Domain:               SERVER
+Logon time:           Wed, 01 Jul 2026 08:30:00 UTC
+Logoff time:          never
+Kickoff time:         never
+Password last set:    Mon, 01 Jun 2026 12:00:00 UTC
+Password can change:  Mon, 01 Jun 2026 12:00:00 UTC
+Password must change: never
+Last bad password   : 0
+Bad password count  : 0
+Logon hours         : FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF
+---------------
+Unix username:        bob
+NT username:
+Account Flags:        [DUL        ]
+User SID:             S-1-5-21-1-2-3-1001
+Full Name:
+Domain:               SERVER
+Logon time:           0
+Logoff time:          Thu, 07 Feb 2036 06:28:15 UTC
+Kickoff time:         Thu, 07 Feb 2036 06:28:15 UTC
+Password last set:    Tue, 02 Jun 2026 09:15:00 +0000
+Password can change:  Tue, 02 Jun 2026 09:15:00 +0000
+Password must change: not a date
+Last bad password   : Wed, 01 Jul 2026 09:00:00 UTC
+Bad password count  : 3
+Logon hours         : FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF
+`
+
+func TestParsePdbeditVerbose(t *testing.T) {
+	accounts := parsePdbeditVerbose(pdbeditSample)
+	if len(accounts) != 2 {
+		t.Fatalf("parsed %d accounts, want 2", len(accounts))
+	}
+
+	alice, bob := accounts[0], accounts[1]
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"alice username", alice.Username, "alice"},
+		{"alice full name", alice.FullName, "Alice Example"},
+		{"alice description", alice.Description, "Accounting"},
+		{"alice SID", alice.SID, "S-1-5-21-1-2-3-1000"},
+		{"alice domain", alice.Domain, "SERVER"},
+		{"alice flags", alice.Flags, "U"},
+		{"alice disabled", alice.Disabled(), false},
+		{"alice locked", alice.Locked(), false},
+		{"alice bad password count", alice.BadPasswordCount, 0},
+		{"bob username", bob.Username, "bob"},
+		{"bob full name", bob.FullName, ""},
+		{"bob flags", bob.Flags, "DUL"},
+		{"bob disabled", bob.Disabled(), true},
+		{"bob locked", bob.Locked(), true},
+		{"bob bad password count", bob.BadPasswordCount, 3},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	times := []struct {
+		name string
+		got  *time.Time
+		want time.Time // Zero for nil
+	}{
+		{"alice logon time", alice.LogonTime, time.Date(2026, time.July, 1, 8, 30, 0, 0, time.UTC)},
+		{"alice logoff time never", alice.LogoffTime, time.Time{}},
+		{"alice password last set", alice.PasswordLastSet, time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)},
+		{"alice last bad password 0", alice.LastBadPassword, time.Time{}},
+		{"bob logon time 0", bob.LogonTime, time.Time{}},
+		{"bob logoff time past 2036", bob.LogoffTime, time.Time{}},
+		{"bob password last set numeric zone", bob.PasswordLastSet, time.Date(2026, time.June, 2, 9, 15, 0, 0, time.UTC)},
+		{"bob password must change unparsable", bob.PasswordMustChange, time.Time{}},
+		{"bob last bad password", bob.LastBadPassword, time.Date(2026, time.July, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range times {
+		switch {
+		case tt.want.IsZero() && tt.got != nil:
+			t.Errorf("%s = %s, want nil", tt.name, tt.got)
+		case !tt.want.IsZero() && (tt.got == nil || !tt.got.Equal(tt.want)):
+			t.Errorf("%s = %v, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParsePdbeditVerboseSkipsEmptyBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"empty output", "", nil},
+		{"separators only", "---------------\n---------------\n", nil},
+		{"block without username", "Account Flags: [U ]\n---------------\nUnix username: carol\n", []string{"carol"}},
+		{"single user without separator", "Unix username: dave\nAccount Flags: [U ]\n", []string{"dave"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := parsePdbeditVerbose(tt.output)
+			if len(accounts) != len(tt.want) {
+				t.Fatalf("parsed %d accounts, want %d", len(accounts), len(tt.want))
+			}
+			for i, account := range accounts {
+				if account.Username != tt.want[i] {
+					t.Errorf("account %d = %s, want %s", i, account.Username, tt.want[i])
+				}
+			}
+		})
+	}
+}
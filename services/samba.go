@@ -3,6 +3,7 @@ package services
 import (
 	"bufio"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/itsHenry35/SambaManager/config"
 	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
 )
 
 var (
@@ -50,7 +52,7 @@ func cleanSubPath(subPath string) (string, error) {
 
 	// Prevent path traversal
 	if strings.Contains(cleaned, "..") {
-		return "", fmt.Errorf("invalid subdirectory path: path traversal not allowed")
+		return "", utils.NewValidationError("invalid subdirectory path: path traversal not allowed")
 	}
 
 	// Empty or "." means no subdirectory
@@ -62,25 +64,55 @@ func cleanSubPath(subPath string) (string, error) {
 }
 
 type SambaService struct {
-	mu sync.RWMutex
+	mu          sync.RWMutex
+	orphanSizes *orphanSizeCalculator
 }
 
 func NewSambaService() *SambaService {
-	return &SambaService{}
+	return &SambaService{
+		orphanSizes: newOrphanSizeCalculator(),
+	}
 }
 
 // CreateUser creates a Samba user with Unix user in extrausers
+// Refuses to reuse a non-empty home directory left behind by a deleted user (see AdoptOrphanedDirectory)
 func (s *SambaService) CreateUser(user *types.User) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.createUserInternal(user, false)
+}
+
+// createUserInternal creates a user without acquiring lock
+// With adoptHome an existing home directory is taken over and never removed on failure
+func (s *SambaService) createUserInternal(user *types.User, adoptHome bool) error {
 	// Validate username (alphanumeric, underscore, dash only)
 	if !isValidUsername(user.Username) {
 		return fmt.Errorf("invalid username: must contain only letters, numbers, underscore, and dash")
 	}
+	// The admin login is checked first, so the name would never reach Samba
+	if IsAdminAccount(user.Username) {
+		return fmt.Errorf("username '%s' is used by a web administrator", user.Username)
+	}
+	if err := checkPasswordPolicy(user.Username, user.Password, false); err != nil {
+		return err
+	}
 
-	// Create home directory for the user (owned by root)
 	homeDir := filepath.Join(config.AppConfig.HomeDir, user.Username)
+	if !adoptHome {
+		if entries, err := os.ReadDir(homeDir); err == nil && len(entries) > 0 {
+			return fmt.Errorf("home directory '%s' already contains data from a deleted user: adopt, archive or delete the orphaned directory first", user.Username)
+		}
+	}
+
+	// Only remove the home directory on failure if it was not adopted
+	cleanupHome := func() {
+		if !adoptHome {
+			_ = os.RemoveAll(homeDir)
+		}
+	}
+
+	// Create home directory for the user (owned by root)
 	if err := os.MkdirAll(homeDir, 0770); err != nil {
 		return fmt.Errorf("failed to create home directory: %v", err)
 	}
@@ -107,7 +139,7 @@ func (s *SambaService) CreateUser(user *types.User) error {
 		"--badname",
 		user.Username)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		_ = os.RemoveAll(homeDir)
+		cleanupHome()
 		return fmt.Errorf("failed to create unix user: %v, output: %s", err, output)
 	}
 
@@ -117,13 +149,13 @@ func (s *SambaService) CreateUser(user *types.User) error {
 	if err != nil {
 		// Cleanup: remove unix user and home directory if Samba user creation fails
 		_ = exec.Command("userdel", "--extrausers", user.Username).Run()
-		_ = os.RemoveAll(homeDir)
+		cleanupHome()
 		return fmt.Errorf("failed to create stdin pipe for smbpasswd: %v", err)
 	}
 
 	if err := cmd.Start(); err != nil {
 		_ = exec.Command("userdel", "--extrausers", user.Username).Run()
-		_ = os.RemoveAll(homeDir)
+		cleanupHome()
 		return fmt.Errorf("failed to start smbpasswd: %v", err)
 	}
 
@@ -133,13 +165,13 @@ func (s *SambaService) CreateUser(user *types.User) error {
 
 	if err != nil {
 		_ = exec.Command("userdel", "--extrausers", user.Username).Run()
-		_ = os.RemoveAll(homeDir)
+		cleanupHome()
 		return fmt.Errorf("failed to write samba password: %v", err)
 	}
 
 	if err := cmd.Wait(); err != nil {
 		_ = exec.Command("userdel", "--extrausers", user.Username).Run()
-		_ = os.RemoveAll(homeDir)
+		cleanupHome()
 		return fmt.Errorf("failed to create samba user: %v", err)
 	}
 
@@ -150,6 +182,10 @@ func (s *SambaService) CreateUser(user *types.User) error {
 		return fmt.Errorf("failed to enable samba user: %v, output: %s", err, output)
 	}
 
+	if err := recordPasswordHistory(user.Username, user.Password); err != nil {
+		log.Printf("Failed to record password history of %s: %v", user.Username, err)
+	}
+
 	return nil
 }
 
@@ -232,6 +268,22 @@ func (s *SambaService) DeleteUser(username string, deleteHomeDir bool) error {
 		if err := os.RemoveAll(homeDir); err != nil {
 			return fmt.Errorf("failed to delete home directory: %v", err)
 		}
+	} else if err := markOrphanFirstSeen(username); err != nil {
+		// The home is still detected as orphaned on the next listing
+		log.Printf("Failed to record orphaned home directory %s: %v", username, err)
+	}
+
+	if err := deleteUserMeta(username); err != nil {
+		log.Printf("Failed to delete metadata of user %s: %v", username, err)
+	}
+	if err := deletePasswordHistory(username); err != nil {
+		log.Printf("Failed to delete password history of user %s: %v", username, err)
+	}
+	if err := deleteSambaAdmin(username); err != nil {
+		log.Printf("Failed to revoke admin roles of user %s: %v", username, err)
+	}
+	if err := deleteTwoFactor(username); err != nil {
+		log.Printf("Failed to delete two-factor authentication of user %s: %v", username, err)
 	}
 
 	// Hot reload Samba configuration
@@ -256,37 +308,62 @@ func removeFromSlice(slice []string, item string) []string {
 	return result
 }
 
-// ListUsers lists all Samba users
+// UserExists checks whether a Samba user exists in tdbsam
+func (s *SambaService) UserExists(username string) bool {
+	if !isValidUsername(username) {
+		return false
+	}
+	cmd := exec.Command("pdbedit", "-L", "-u", username)
+	_, err := cmd.CombinedOutput()
+	return err == nil
+}
+
+// ListUsers lists all Samba users with their account status
 func (s *SambaService) ListUsers() ([]types.UserResponse, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	cmd := exec.Command("pdbedit", "-L")
-	output, err := cmd.CombinedOutput()
+	accounts, err := listSambaAccounts()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list samba users: %v, output: %s", err, output)
+		return nil, err
+	}
+
+	meta, err := loadUserMeta()
+	if err != nil {
+		return nil, err
 	}
 
 	var users []types.UserResponse
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.Split(line, ":")
-		if len(parts) > 0 {
-			username := parts[0]
-			homeDir := filepath.Join(config.AppConfig.HomeDir, username)
-			users = append(users, types.UserResponse{
-				Username: username,
-				HomeDir:  homeDir,
-			})
-		}
+	for i := range accounts {
+		users = append(users, newUserResponse(&accounts[i], meta[accounts[i].Username]))
 	}
 
 	return users, nil
 }
 
+// newUserResponse builds the user information returned to clients
+func newUserResponse(account *sambaAccount, meta userMeta) types.UserResponse {
+	return types.UserResponse{
+		Username:         account.Username,
+		DisplayName:      account.FullName,
+		Email:            meta.Email,
+		Department:       meta.Department,
+		HomeDir:          filepath.Join(config.AppConfig.HomeDir, account.Username),
+		Disabled:         account.Disabled(),
+		Locked:           account.Locked(),
+		AccountFlags:     account.Flags,
+		PasswordLastSet:  account.PasswordLastSet,
+		LogonTime:        account.LogonTime,
+		LogoffTime:       account.LogoffTime,
+		BadPasswordCount: account.BadPasswordCount,
+
+		MustChangePassword: meta.MustChangePassword,
+		PasswordMaxAgeDays: meta.PasswordMaxAgeDays,
+		PasswordExpiresAt:  passwordExpiresAt(account, meta),
+		PasswordExpired:    passwordExpired(account, meta),
+	}
+}
+
 // CreateShare creates a Samba share for a user's directory (supports multiple shares per owner)
 func (s *SambaService) CreateShare(share *types.Share) (string, error) {
 	s.mu.Lock()
@@ -743,6 +820,9 @@ func (s *SambaService) ChangePassword(username string, newPassword string) error
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("user does not exist or failed to verify user: %v, output: %s", err, output)
 	}
+	if err := checkPasswordPolicy(username, newPassword, true); err != nil {
+		return err
+	}
 
 	// Change Samba password using smbpasswd (without -a flag for existing users)
 	cmd = exec.Command("smbpasswd", "-s", username)
@@ -767,6 +847,13 @@ func (s *SambaService) ChangePassword(username string, newPassword string) error
 		return fmt.Errorf("failed to change password: %v", err)
 	}
 
+	if err := recordPasswordHistory(username, newPassword); err != nil {
+		log.Printf("Failed to record password history of %s: %v", username, err)
+	}
+	if err := clearPasswordChangeInternal(username); err != nil {
+		log.Printf("Failed to reset password expiry of %s: %v", username, err)
+	}
+
 	return nil
 }
 
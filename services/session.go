@@ -0,0 +1,306 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+const (
+	sessionsFileName = "sessions.json"
+
+	// sessionTouchInterval limits how often the last seen time of a session is written to disk
+	sessionTouchInterval = time.Minute
+)
+
+// storedSession is a session with the secrets that are never returned by the API
+type storedSession struct {
+	types.Session
+	RefreshTokenHash string `json:"refresh_token_hash,omitempty"` // Empty for sessions that can't be renewed
+	ClientHash       string `json:"client_hash,omitempty"`        // Binds the refresh token to the browser's client secret cookie
+}
+
+// sessionStore keeps web sessions in memory, since they are checked on every request
+type sessionStore struct {
+	mu       sync.Mutex
+	loaded   bool
+	sessions map[string]*storedSession
+}
+
+var sessions = &sessionStore{}
+
+// loadInternal reads the sessions file on first use (must hold lock)
+func (s *sessionStore) loadInternal() error {
+	if s.loaded {
+		return nil
+	}
+
+	var list []storedSession
+	if err := readJSONFile(dataFilePath(sessionsFileName), &list); err != nil {
+		return err
+	}
+
+	s.sessions = make(map[string]*storedSession, len(list))
+	for i := range list {
+		s.sessions[list[i].ID] = &list[i]
+	}
+	s.loaded = true
+	return nil
+}
+
+// saveInternal drops expired sessions and writes the rest (must hold lock)
+func (s *sessionStore) saveInternal() error {
+	now := time.Now()
+	list := make([]storedSession, 0, len(s.sessions))
+	for id, session := range s.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(s.sessions, id)
+			continue
+		}
+		list = append(list, *session)
+	}
+
+	return writeJSONFile(dataFilePath(sessionsFileName), list)
+}
+
+// hashSessionSecret returns the stored form of a refresh token or client identifier
+func hashSessionSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshTokenInternal generates a refresh token for a session and stores its hash (must hold lock)
+// The token starts with the session ID, so a reused old token still identifies its session
+func newRefreshTokenInternal(session *storedSession) (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	token := session.ID + "." + base64.RawURLEncoding.EncodeToString(bytes)
+	session.RefreshTokenHash = hashSessionSecret(token)
+	return token, nil
+}
+
+// CreateSession records a new web session and returns it
+// Sessions with a client secret also get a refresh token bound to it, otherwise the token is empty
+func CreateSession(username string, ip string, userAgent string, expiresAt time.Time, clientSecret string) (*types.Session, string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate session ID: %v", err)
+	}
+
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &storedSession{
+		Session: types.Session{
+			ID:         hex.EncodeToString(bytes),
+			Username:   username,
+			IP:         ip,
+			UserAgent:  userAgent,
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+		},
+	}
+
+	refreshToken := ""
+	if clientSecret != "" {
+		var err error
+		if refreshToken, err = newRefreshTokenInternal(session); err != nil {
+			return nil, "", err
+		}
+		session.ClientHash = hashSessionSecret(clientSecret)
+	}
+
+	sessions.sessions[session.ID] = session
+	if err := sessions.saveInternal(); err != nil {
+		delete(sessions.sessions, session.ID)
+		return nil, "", err
+	}
+
+	copy := session.Session
+	return &copy, refreshToken, nil
+}
+
+// RefreshSession exchanges a refresh token for a new one, returning the renewed session
+// Each refresh token works once and only with the client secret of the browser it was issued to:
+// presenting an already used token, or one without that secret, means it was stolen, so the whole session is signed out
+func RefreshSession(refreshToken string, clientSecret string, ip string) (*types.Session, string, error) {
+	invalid := utils.NewUnauthorizedError("Invalid or expired refresh token")
+
+	id, _, found := strings.Cut(refreshToken, ".")
+	if !found {
+		return nil, "", invalid
+	}
+
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		return nil, "", err
+	}
+
+	session, ok := sessions.sessions[id]
+	if !ok || session.RefreshTokenHash == "" || !session.ExpiresAt.After(time.Now()) {
+		return nil, "", invalid
+	}
+
+	hashMatches := subtle.ConstantTimeCompare([]byte(session.RefreshTokenHash), []byte(hashSessionSecret(refreshToken))) == 1
+	clientMatches := subtle.ConstantTimeCompare([]byte(session.ClientHash), []byte(hashSessionSecret(clientSecret))) == 1
+	if !hashMatches || !clientMatches {
+		log.Printf("Refresh token reuse detected for session of %s from %s, signing out the session", session.Username, ip)
+		delete(sessions.sessions, id)
+		if err := sessions.saveInternal(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", invalid
+	}
+
+	previousHash := session.RefreshTokenHash
+	newToken, err := newRefreshTokenInternal(session)
+	if err != nil {
+		return nil, "", err
+	}
+	session.LastSeenAt = time.Now()
+	session.IP = ip
+	if err := sessions.saveInternal(); err != nil {
+		// Keep the presented token usable, the client never received the new one
+		session.RefreshTokenHash = previousHash
+		return nil, "", err
+	}
+
+	copy := session.Session
+	return &copy, newToken, nil
+}
+
+// ValidateSession reports whether a session exists, belongs to the user and has not expired
+// The session's last seen time and address are updated, at most once per sessionTouchInterval
+func ValidateSession(id string, username string, ip string) bool {
+	if id == "" {
+		return false
+	}
+
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		log.Printf("Failed to read sessions: %v", err)
+		return false
+	}
+
+	session, ok := sessions.sessions[id]
+	now := time.Now()
+	if !ok || session.Username != username || !session.ExpiresAt.After(now) {
+		return false
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		session.LastSeenAt = now
+		session.IP = ip
+		if err := sessions.saveInternal(); err != nil {
+			log.Printf("Failed to save sessions: %v", err)
+		}
+	}
+
+	return true
+}
+
+// GetSessionUsername returns the user a session belongs to
+func GetSessionUsername(id string) (string, bool) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		return "", false
+	}
+
+	session, ok := sessions.sessions[id]
+	if !ok {
+		return "", false
+	}
+	return session.Username, true
+}
+
+// RevokeSession signs out a single session
+// With a username, only a session of that user can be revoked
+func RevokeSession(id string, username string) error {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		return err
+	}
+
+	session, ok := sessions.sessions[id]
+	if !ok || (username != "" && session.Username != username) {
+		return utils.NewNotFoundError("Session not found")
+	}
+
+	delete(sessions.sessions, id)
+	return sessions.saveInternal()
+}
+
+// RevokeUserSessions signs out all sessions of a user except exceptID, returning how many were revoked
+// Used when the user signs out everywhere, or their password, roles or account change
+func RevokeUserSessions(username string, exceptID string) (int, error) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for id, session := range sessions.sessions {
+		if session.Username == username && id != exceptID {
+			delete(sessions.sessions, id)
+			revoked++
+		}
+	}
+	if revoked == 0 {
+		return 0, nil
+	}
+
+	return revoked, sessions.saveInternal()
+}
+
+// ListSessions lists active sessions, newest first (username "" lists all users)
+func ListSessions(username string) ([]types.Session, error) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+
+	if err := sessions.loadInternal(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	list := []types.Session{}
+	for _, session := range sessions.sessions {
+		if session.ExpiresAt.After(now) && (username == "" || session.Username == username) {
+			list = append(list, session.Session)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeenAt.After(list[j].LastSeenAt)
+	})
+
+	return list, nil
+}
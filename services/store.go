@@ -0,0 +1,57 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/itsHenry35/SambaManager/config"
+)
+
+// dataFilePath returns the path of a state file inside the configured data directory
+func dataFilePath(name string) string {
+	return filepath.Join(config.AppConfig.DataDir, name)
+}
+
+// readJSONFile loads a JSON state file into v
+// A missing file is not an error and leaves v untouched
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", filepath.Base(path), err)
+	}
+
+	return nil
+}
+
+// writeJSONFile atomically writes v as JSON to a state file (readable by root only)
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", filepath.Base(path), err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated state file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %v", filepath.Base(path), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors, base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	// The RFC lists 8 digit codes, these are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, totpCode(key, current), 0, current, true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", totpCode(key, current), 0, current, true},
+		{"previous step", rfcSecret, totpCode(key, current-1), 0, current - 1, true},
+		{"next step", rfcSecret, totpCode(key, current+1), 0, current + 1, true},
+		{"outside skew before", rfcSecret, totpCode(key, current-2), 0, 0, false},
+		{"outside skew after", rfcSecret, totpCode(key, current+2), 0, 0, false},
+		{"replayed step", rfcSecret, totpCode(key, current), current, 0, false},
+		{"older than last step", rfcSecret, totpCode(key, current-1), current - 1, 0, false},
+		{"newer than last step", rfcSecret, totpCode(key, current+1), current, current + 1, true},
+		{"wrong code", rfcSecret, "000000", 0, 0, false},
+		{"too short", rfcSecret, totpCode(key, current)[:5], 0, 0, false},
+		{"too long", rfcSecret, totpCode(key, current) + "0", 0, 0, false},
+		{"invalid secret", "not base32!", totpCode(key, current), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := verifyTOTP(tt.secret, tt.code, tt.lastStep, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("verifyTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
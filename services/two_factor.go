@@ -0,0 +1,416 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	twoFactorFileName       = "two_factor.json"
+	twoFactorPolicyFileName = "two_factor_policy.json"
+
+	recoveryCodeCount = 10
+
+	loginChallengeLifetime    = 5 * time.Minute
+	maxLoginChallengeAttempts = 5
+
+	// LoginFailureInvalidTwoFactorCode is recorded when the second login step fails
+	LoginFailureInvalidTwoFactorCode = "invalid_two_factor_code"
+)
+
+// twoFactorAccount is the stored TOTP enrollment of an account
+type twoFactorAccount struct {
+	Secret             string     `json:"secret"`
+	Enabled            bool       `json:"enabled"` // False until the first code was confirmed
+	ConfirmedAt        *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodeHashes []string   `json:"recovery_code_hashes,omitempty"`
+	LastUsedStep       int64      `json:"last_used_step"` // Time step of the last accepted code, to reject replays
+}
+
+// twoFactorMu guards the two-factor enrollment and policy files
+var twoFactorMu sync.Mutex
+
+// loadTwoFactorInternal reads all two-factor enrollments (must hold twoFactorMu)
+func loadTwoFactorInternal() (map[string]*twoFactorAccount, error) {
+	accounts := make(map[string]*twoFactorAccount)
+	if err := readJSONFile(dataFilePath(twoFactorFileName), &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// updateTwoFactor applies fn to the two-factor enrollments and saves them unless fn fails
+func updateTwoFactor(fn func(accounts map[string]*twoFactorAccount) error) error {
+	twoFactorMu.Lock()
+	defer twoFactorMu.Unlock()
+
+	accounts, err := loadTwoFactorInternal()
+	if err != nil {
+		return err
+	}
+	if err := fn(accounts); err != nil {
+		return err
+	}
+	return writeJSONFile(dataFilePath(twoFactorFileName), accounts)
+}
+
+// defaultTwoFactorPolicy requires two-factor authentication for admins only
+func defaultTwoFactorPolicy() *types.TwoFactorPolicy {
+	return &types.TwoFactorPolicy{
+		RequireForAdmins: true,
+	}
+}
+
+// GetTwoFactorPolicy reads the two-factor policy
+func GetTwoFactorPolicy() (*types.TwoFactorPolicy, error) {
+	twoFactorMu.Lock()
+	defer twoFactorMu.Unlock()
+
+	policy := defaultTwoFactorPolicy()
+	if err := readJSONFile(dataFilePath(twoFactorPolicyFileName), policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdateTwoFactorPolicy replaces the two-factor policy
+// Accounts that become required to use it have to set it up at their next login
+func UpdateTwoFactorPolicy(policy *types.TwoFactorPolicy) error {
+	twoFactorMu.Lock()
+	defer twoFactorMu.Unlock()
+
+	return writeJSONFile(dataFilePath(twoFactorPolicyFileName), policy)
+}
+
+// twoFactorRequired reports whether the policy requires two-factor authentication for an account
+func twoFactorRequired(admin bool) bool {
+	policy, err := GetTwoFactorPolicy()
+	if err != nil {
+		// Fail closed for admins, whose accounts can do the most damage
+		return admin
+	}
+	if admin {
+		return policy.RequireForAdmins
+	}
+	return policy.RequireForUsers
+}
+
+// TwoFactorEnabled reports whether an account has confirmed two-factor authentication
+func TwoFactorEnabled(username string) bool {
+	twoFactorMu.Lock()
+	defer twoFactorMu.Unlock()
+
+	accounts, err := loadTwoFactorInternal()
+	if err != nil {
+		return false
+	}
+	account, ok := accounts[username]
+	return ok && account.Enabled
+}
+
+// TwoFactorSetupRequired reports whether an account must set up two-factor authentication before using the web UI
+func TwoFactorSetupRequired(username string, admin bool) bool {
+	return twoFactorRequired(admin) && !TwoFactorEnabled(username)
+}
+
+// GetTwoFactorStatus returns the two-factor state of an account
+func GetTwoFactorStatus(username string, admin bool) (*types.TwoFactorStatus, error) {
+	required := twoFactorRequired(admin)
+
+	twoFactorMu.Lock()
+	defer twoFactorMu.Unlock()
+
+	accounts, err := loadTwoFactorInternal()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &types.TwoFactorStatus{Required: required}
+	if account, ok := accounts[username]; ok {
+		status.Enabled = account.Enabled
+		status.SetupPending = !account.Enabled
+		status.ConfirmedAt = account.ConfirmedAt
+		status.RecoveryCodesLeft = len(account.RecoveryCodeHashes)
+	}
+	return status, nil
+}
+
+// BeginTwoFactorSetup generates a new TOTP secret for an account, replacing an unconfirmed one
+// Two-factor authentication only becomes active once a code from the secret is confirmed
+func BeginTwoFactorSetup(username string) (*types.TwoFactorSetupResponse, error) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		if account, ok := accounts[username]; ok && account.Enabled {
+			return utils.NewForbiddenError("Two-factor authentication is already enabled, disable it first")
+		}
+		accounts[username] = &twoFactorAccount{Secret: secret}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	uri := totpProvisioningURI(username, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %v", err)
+	}
+
+	return &types.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTwoFactorSetup enables two-factor authentication with a code from the new secret
+// Returns the recovery codes, which are only stored hashed
+func ConfirmTwoFactorSetup(username string, code string) (*types.TwoFactorRecoveryCodesResponse, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		account, ok := accounts[username]
+		if !ok || account.Enabled {
+			return utils.NewNotFoundError("No two-factor setup in progress")
+		}
+
+		step, valid := verifyTOTP(account.Secret, normalizeTwoFactorCode(code), account.LastUsedStep, time.Now())
+		if !valid {
+			return utils.NewValidationError("Invalid two-factor code")
+		}
+
+		now := time.Now()
+		account.Enabled = true
+		account.ConfirmedAt = &now
+		account.LastUsedStep = step
+		account.RecoveryCodeHashes = hashes
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &types.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of an account after checking a current code
+func RegenerateRecoveryCodes(username string, code string) (*types.TwoFactorRecoveryCodesResponse, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		account, ok := accounts[username]
+		if !ok || !account.Enabled {
+			return utils.NewNotFoundError("Two-factor authentication is not enabled")
+		}
+		if !verifyTwoFactorCodeInternal(account, code) {
+			return utils.NewValidationError("Invalid two-factor code")
+		}
+		account.RecoveryCodeHashes = hashes
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &types.TwoFactorRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication of an account after checking a current code
+// Not allowed while the policy requires it for the account
+func DisableTwoFactor(username string, code string, admin bool) error {
+	if twoFactorRequired(admin) {
+		return utils.NewForbiddenError("Two-factor authentication is required for your account")
+	}
+
+	return updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		account, ok := accounts[username]
+		if !ok || !account.Enabled {
+			return utils.NewNotFoundError("Two-factor authentication is not enabled")
+		}
+		if !verifyTwoFactorCodeInternal(account, code) {
+			return utils.NewValidationError("Invalid two-factor code")
+		}
+		delete(accounts, username)
+		return nil
+	})
+}
+
+// ResetTwoFactor removes the two-factor enrollment of an account that lost its authenticator (admin)
+func ResetTwoFactor(username string) error {
+	return updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		if _, ok := accounts[username]; !ok {
+			return utils.NewNotFoundError("Two-factor authentication is not set up for this account")
+		}
+		delete(accounts, username)
+		return nil
+	})
+}
+
+// deleteTwoFactor forgets the two-factor enrollment of a deleted account
+func deleteTwoFactor(username string) error {
+	return updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		delete(accounts, username)
+		return nil
+	})
+}
+
+// renameTwoFactor moves the two-factor enrollment of a renamed user
+func renameTwoFactor(oldName string, newName string) error {
+	return updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		if account, ok := accounts[oldName]; ok {
+			accounts[newName] = account
+			delete(accounts, oldName)
+		}
+		return nil
+	})
+}
+
+// verifyTwoFactorCodeInternal accepts a TOTP code or an unused recovery code, which is then consumed (must hold twoFactorMu)
+func verifyTwoFactorCodeInternal(account *twoFactorAccount, code string) bool {
+	code = normalizeTwoFactorCode(code)
+
+	if step, ok := verifyTOTP(account.Secret, code, account.LastUsedStep, time.Now()); ok {
+		account.LastUsedStep = step
+		return true
+	}
+
+	hash := hashSessionSecret(code)
+	for i, stored := range account.RecoveryCodeHashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			account.RecoveryCodeHashes = append(account.RecoveryCodeHashes[:i], account.RecoveryCodeHashes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTwoFactorCode removes the separators users may type in codes
+func normalizeTwoFactorCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 8)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(bytes))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashSessionSecret(code))
+	}
+	return codes, hashes, nil
+}
+
+// loginChallenge is a login that passed the password check and waits for the second factor
+type loginChallenge struct {
+	username               string
+	passwordChangeRequired bool
+	expiresAt              time.Time
+	attempts               int
+}
+
+// loginChallengeStore keeps pending two-step logins in memory, they only live for a few minutes
+type loginChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]*loginChallenge
+}
+
+var loginChallenges = &loginChallengeStore{
+	challenges: make(map[string]*loginChallenge),
+}
+
+// CreateLoginChallenge starts the second login step for an account whose password was verified
+// The returned token identifies the login in CompleteLoginChallenge
+func CreateLoginChallenge(username string, passwordChangeRequired bool) (string, time.Time, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate login challenge: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+
+	loginChallenges.mu.Lock()
+	defer loginChallenges.mu.Unlock()
+
+	now := time.Now()
+	for hash, challenge := range loginChallenges.challenges {
+		if !challenge.expiresAt.After(now) {
+			delete(loginChallenges.challenges, hash)
+		}
+	}
+
+	expiresAt := now.Add(loginChallengeLifetime)
+	loginChallenges.challenges[hashSessionSecret(token)] = &loginChallenge{
+		username:               username,
+		passwordChangeRequired: passwordChangeRequired,
+		expiresAt:              expiresAt,
+	}
+	return token, expiresAt, nil
+}
+
+// LoginChallengeUsername returns the account of a pending login, used to apply login throttling
+func LoginChallengeUsername(token string) (string, bool) {
+	loginChallenges.mu.Lock()
+	defer loginChallenges.mu.Unlock()
+
+	challenge, ok := loginChallenges.challenges[hashSessionSecret(token)]
+	if !ok || !challenge.expiresAt.After(time.Now()) {
+		return "", false
+	}
+	return challenge.username, true
+}
+
+// CompleteLoginChallenge checks the second factor of a pending login and ends the challenge on success
+// Too many wrong codes end the challenge as well, so the password has to be entered again
+func CompleteLoginChallenge(token string, code string) (string, bool, error) {
+	loginChallenges.mu.Lock()
+	defer loginChallenges.mu.Unlock()
+
+	hash := hashSessionSecret(token)
+	challenge, ok := loginChallenges.challenges[hash]
+	if !ok || !challenge.expiresAt.After(time.Now()) {
+		delete(loginChallenges.challenges, hash)
+		return "", false, utils.NewUnauthorizedError("Login expired, please sign in again")
+	}
+
+	var valid bool
+	if err := updateTwoFactor(func(accounts map[string]*twoFactorAccount) error {
+		if account, ok := accounts[challenge.username]; ok && account.Enabled {
+			valid = verifyTwoFactorCodeInternal(account, code)
+		}
+		return nil
+	}); err != nil {
+		return "", false, err
+	}
+
+	if !valid {
+		challenge.attempts++
+		if challenge.attempts >= maxLoginChallengeAttempts {
+			delete(loginChallenges.challenges, hash)
+		}
+		return "", false, utils.NewUnauthorizedError("Invalid two-factor code")
+	}
+
+	delete(loginChallenges.challenges, hash)
+	return challenge.username, challenge.passwordChangeRequired, nil
+}
@@ -0,0 +1,454 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+)
+
+const (
+	// MaxImportRows is the maximum number of users in a single import
+	MaxImportRows = 1000
+
+	generatedPasswordLength   = 16
+	generatedPasswordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	generatedPasswordSymbols  = "!#%+-=?@_"
+)
+
+// userCSVColumns are the columns of an exported CSV file (imports add a password column)
+var userCSVColumns = []string{"username", "display_name", "email", "department", "home_dir", "shares"}
+
+// ParseUserImport parses a CSV or JSON list of users to import
+// CSV files need a header row, lists in cells are separated by ";" and shares are
+// written as "name:user1|user2[:ro|rw][:sub/path]"
+func ParseUserImport(data []byte, format string) ([]types.ImportUserRow, error) {
+	var rows []types.ImportUserRow
+
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	case "csv":
+		var err error
+		rows, err = parseUserCSV(data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no users to import")
+	}
+	if len(rows) > MaxImportRows {
+		return nil, fmt.Errorf("too many users: at most %d can be imported at once", MaxImportRows)
+	}
+
+	return rows, nil
+}
+
+// parseUserCSV parses a CSV import file (unknown columns such as home_dir are ignored)
+func parseUserCSV(data []byte) ([]types.ImportUserRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"username", "password"} {
+		if _, found := columns[required]; !found {
+			return nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+
+	cell := func(record []string, column string) string {
+		if i, found := columns[column]; found && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := make([]types.ImportUserRow, 0, len(records)-1)
+	for line, record := range records[1:] {
+		row := types.ImportUserRow{
+			Username:    cell(record, "username"),
+			Password:    cell(record, "password"),
+			Groups:      splitList(cell(record, "groups"), ";"),
+			DisplayName: cell(record, "display_name"),
+			Email:       cell(record, "email"),
+			Department:  cell(record, "department"),
+		}
+
+		if quota := cell(record, "quota_mb"); quota != "" {
+			row.QuotaMB, err = strconv.ParseInt(quota, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid quota_mb: %s", line+1, quota)
+			}
+		}
+
+		for _, spec := range splitList(cell(record, "shares"), ";") {
+			share, err := parseShareSpec(spec)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", line+1, err)
+			}
+			row.Shares = append(row.Shares, share)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseShareSpec parses a share written as "name:user1|user2[:ro|rw][:sub/path]"
+func parseShareSpec(spec string) (types.UserShareSpec, error) {
+	parts := strings.SplitN(spec, ":", 4)
+	if len(parts) < 2 {
+		return types.UserShareSpec{}, fmt.Errorf("invalid share %q: expected name:user1|user2[:ro|rw][:sub/path]", spec)
+	}
+
+	share := types.UserShareSpec{
+		Name:       strings.TrimSpace(parts[0]),
+		SharedWith: splitList(parts[1], "|"),
+	}
+	if len(parts) > 2 {
+		switch strings.TrimSpace(parts[2]) {
+		case "ro":
+			share.ReadOnly = true
+		case "", "rw":
+		default:
+			return types.UserShareSpec{}, fmt.Errorf("invalid share %q: access must be ro or rw", spec)
+		}
+	}
+	if len(parts) > 3 {
+		share.SubPath = strings.TrimSpace(parts[3])
+	}
+
+	return share, nil
+}
+
+// formatShareSpec writes a share in the CSV share syntax
+func formatShareSpec(share types.UserShareSpec) string {
+	access := "rw"
+	if share.ReadOnly {
+		access = "ro"
+	}
+	spec := share.Name + ":" + strings.Join(share.SharedWith, "|") + ":" + access
+	if share.SubPath != "" {
+		spec += ":" + share.SubPath
+	}
+	return spec
+}
+
+// splitList splits a separated list, dropping empty items
+func splitList(value string, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ValidateUserImport checks every row before anything is created
+// Returns a result per row and whether all rows are valid
+func (s *SambaService) ValidateUserImport(rows []types.ImportUserRow) ([]types.ImportUserResult, bool, error) {
+	users, err := s.ListUsers()
+	if err != nil {
+		return nil, false, err
+	}
+	existing := make(map[string]bool, len(users))
+	for _, user := range users {
+		existing[user.Username] = true
+	}
+
+	policy, err := loadPasswordPolicy()
+	if err != nil {
+		return nil, false, err
+	}
+
+	results := make([]types.ImportUserResult, len(rows))
+	seen := make(map[string]bool, len(rows))
+	allValid := true
+	for i, row := range rows {
+		problems := validateImportRow(&row, policy, existing, seen)
+		seen[row.Username] = true
+
+		results[i] = types.ImportUserResult{
+			Row:      i + 1,
+			Username: row.Username,
+			Status:   types.ImportStatusValid,
+		}
+		if len(problems) > 0 {
+			results[i].Status = types.ImportStatusInvalid
+			results[i].Error = strings.Join(problems, "; ")
+			allValid = false
+		}
+	}
+
+	return results, allValid, nil
+}
+
+// validateImportRow returns every problem found in an import row
+func validateImportRow(row *types.ImportUserRow, policy *types.PasswordPolicy, existing map[string]bool, seen map[string]bool) []string {
+	var problems []string
+
+	switch {
+	case !isValidUsername(row.Username):
+		problems = append(problems, "invalid username: must contain only letters, numbers, underscore, and dash")
+	case existing[row.Username]:
+		problems = append(problems, "user already exists")
+	case seen[row.Username]:
+		problems = append(problems, "duplicate username in import")
+	default:
+		homeDir := filepath.Join(config.AppConfig.HomeDir, row.Username)
+		if entries, err := os.ReadDir(homeDir); err == nil && len(entries) > 0 {
+			problems = append(problems, "home directory already contains data from a deleted user")
+		}
+	}
+
+	if row.Password != types.GeneratePassword {
+		problems = append(problems, passwordPolicyViolations(policy, row.Username, row.Password)...)
+	}
+
+	// Samba users are not system group members and quotas are not managed here, so these can't take effect
+	if len(row.Groups) > 0 {
+		problems = append(problems, "groups are not supported: Samba users can't be added to system groups")
+	}
+	if row.QuotaMB != 0 {
+		problems = append(problems, "quota_mb is not supported: disk quotas are not managed by SambaManager")
+	}
+
+	if err := validateDisplayName(row.DisplayName); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if row.Email != "" {
+		if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email || len(row.Email) > 254 {
+			problems = append(problems, fmt.Sprintf("invalid email: %s", row.Email))
+		}
+	}
+	if len(row.Department) > 64 {
+		problems = append(problems, "department must be at most 64 characters")
+	}
+
+	shareNames := make(map[string]bool)
+	for _, share := range row.Shares {
+		if !isValidShareName(share.Name) {
+			problems = append(problems, fmt.Sprintf("invalid share name: %s", share.Name))
+		}
+		// Unnamed shares are named by timestamp, so only one can be created at once
+		if shareNames[share.Name] {
+			problems = append(problems, fmt.Sprintf("duplicate share name: %q", share.Name))
+		}
+		shareNames[share.Name] = true
+
+		if len(share.SharedWith) == 0 {
+			problems = append(problems, fmt.Sprintf("share %q must be shared with at least one user", share.Name))
+		}
+		for _, username := range share.SharedWith {
+			if !isValidUsername(username) {
+				problems = append(problems, fmt.Sprintf("invalid username in share %q: %s", share.Name, username))
+			}
+		}
+		if _, err := cleanSubPath(share.SubPath); err != nil {
+			problems = append(problems, fmt.Sprintf("share %q: %v", share.Name, err))
+		}
+	}
+
+	return problems
+}
+
+// ImportUser creates one validated import row: the user, its metadata and its shares
+// The result is updated with the generated password and the created share IDs
+func (s *SambaService) ImportUser(row *types.ImportUserRow, result *types.ImportUserResult) error {
+	password := row.Password
+	if password == types.GeneratePassword {
+		var err error
+		password, err = generatePolicyPassword(row.Username)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.CreateUser(&types.User{Username: row.Username, Password: password}); err != nil {
+		return err
+	}
+	if row.Password == types.GeneratePassword {
+		result.GeneratedPassword = password
+	}
+
+	if err := updateUserMeta(row.Username, func(meta *userMeta) {
+		meta.Email = row.Email
+		meta.Department = row.Department
+	}); err != nil {
+		return fmt.Errorf("user created but failed to save metadata: %v", err)
+	}
+
+	if row.DisplayName != "" {
+		s.mu.Lock()
+		err := s.setDisplayNameInternal(row.Username, row.DisplayName)
+		s.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("user created but %v", err)
+		}
+	}
+
+	for _, spec := range row.Shares {
+		shareID, err := s.CreateShare(&types.Share{
+			Name:       spec.Name,
+			Owner:      row.Username,
+			SharedWith: spec.SharedWith,
+			ReadOnly:   spec.ReadOnly,
+			SubPath:    spec.SubPath,
+		})
+		if err != nil {
+			return fmt.Errorf("user created but failed to create share %q: %v", spec.Name, err)
+		}
+		result.Shares = append(result.Shares, shareID)
+	}
+
+	return nil
+}
+
+// generatePolicyPassword generates a random password that satisfies the password policy
+func generatePolicyPassword(username string) (string, error) {
+	policy, err := loadPasswordPolicy()
+	if err != nil {
+		return "", err
+	}
+
+	length := generatedPasswordLength
+	if policy.MinLength > length {
+		length = policy.MinLength
+	}
+	alphabet := generatedPasswordAlphabet
+	if policy.RequireSymbol {
+		alphabet += generatedPasswordSymbols
+	}
+
+	// Random passwords rarely miss a character class, retry until one fits
+	for i := 0; i < 100; i++ {
+		password, err := generatePassword(alphabet, length)
+		if err != nil {
+			return "", err
+		}
+		if len(passwordPolicyViolations(policy, username, password)) == 0 {
+			return password, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to generate a password matching the password policy")
+}
+
+// generatePassword generates a random password without ambiguous characters
+func generatePassword(alphabet string, length int) (string, error) {
+	alphabetSize := big.NewInt(int64(len(alphabet)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %v", err)
+		}
+		password[i] = alphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// ExportUsers lists all users with their metadata and owned shares (never passwords)
+func (s *SambaService) ExportUsers() ([]types.ExportUser, error) {
+	users, err := s.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.ListShares()
+	if err != nil {
+		return nil, err
+	}
+
+	sharesByOwner := make(map[string][]types.UserShareSpec)
+	for _, share := range shares {
+		sharesByOwner[share.Owner] = append(sharesByOwner[share.Owner], types.UserShareSpec{
+			Name:       strings.TrimPrefix(share.ID, share.Owner+"-share-"),
+			SharedWith: share.SharedWith,
+			ReadOnly:   share.ReadOnly,
+			SubPath:    share.SubPath,
+		})
+	}
+
+	exported := make([]types.ExportUser, 0, len(users))
+	for _, user := range users {
+		shares := sharesByOwner[user.Username]
+		if shares == nil {
+			shares = []types.UserShareSpec{}
+		}
+		exported = append(exported, types.ExportUser{
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			Email:       user.Email,
+			Department:  user.Department,
+			HomeDir:     user.HomeDir,
+			Shares:      shares,
+		})
+	}
+
+	sort.Slice(exported, func(i, j int) bool {
+		return exported[i].Username < exported[j].Username
+	})
+
+	return exported, nil
+}
+
+// WriteUserExportCSV writes exported users as CSV in the import syntax (without a password column)
+func WriteUserExportCSV(w io.Writer, users []types.ExportUser) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(userCSVColumns); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		shares := make([]string, 0, len(user.Shares))
+		for _, share := range user.Shares {
+			shares = append(shares, formatShareSpec(share))
+		}
+		record := []string{
+			user.Username,
+			user.DisplayName,
+			user.Email,
+			user.Department,
+			user.HomeDir,
+			strings.Join(shares, ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
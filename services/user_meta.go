@@ -0,0 +1,89 @@
+package services
+
+import "sync"
+
+const userMetaFileName = "user_meta.json"
+
+// userMeta is metadata stored for a user outside of Samba
+type userMeta struct {
+	Email      string `json:"email,omitempty"`
+	Department string `json:"department,omitempty"`
+	Notes      string `json:"notes,omitempty"`
+
+	MustChangePassword bool `json:"must_change_password,omitempty"`  // Password must be changed at the next login
+	PasswordMaxAgeDays int  `json:"password_max_age_days,omitempty"` // Password expires this many days after it was set (0 = never)
+}
+
+// isEmpty reports whether no metadata is set
+func (m *userMeta) isEmpty() bool {
+	return m.Email == "" && m.Department == "" && m.Notes == "" &&
+		!m.MustChangePassword && m.PasswordMaxAgeDays == 0
+}
+
+// userMetaMu guards the user metadata file
+var userMetaMu sync.Mutex
+
+// loadUserMeta reads the metadata of all users
+func loadUserMeta() (map[string]userMeta, error) {
+	userMetaMu.Lock()
+	defer userMetaMu.Unlock()
+
+	return loadUserMetaInternal()
+}
+
+// loadUserMetaInternal reads the metadata of all users (caller must hold userMetaMu)
+func loadUserMetaInternal() (map[string]userMeta, error) {
+	meta := make(map[string]userMeta)
+	if err := readJSONFile(dataFilePath(userMetaFileName), &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// updateUserMeta applies fn to a user's metadata and saves it (empty metadata is removed)
+func updateUserMeta(username string, fn func(meta *userMeta)) error {
+	userMetaMu.Lock()
+	defer userMetaMu.Unlock()
+
+	all, err := loadUserMetaInternal()
+	if err != nil {
+		return err
+	}
+
+	meta := all[username]
+	fn(&meta)
+	if meta.isEmpty() {
+		delete(all, username)
+	} else {
+		all[username] = meta
+	}
+
+	return writeJSONFile(dataFilePath(userMetaFileName), all)
+}
+
+// deleteUserMeta forgets a user's metadata
+func deleteUserMeta(username string) error {
+	return updateUserMeta(username, func(meta *userMeta) {
+		*meta = userMeta{}
+	})
+}
+
+// renameUserMeta moves a user's metadata to a new username
+func renameUserMeta(oldName string, newName string) error {
+	userMetaMu.Lock()
+	defer userMetaMu.Unlock()
+
+	all, err := loadUserMetaInternal()
+	if err != nil {
+		return err
+	}
+
+	meta, ok := all[oldName]
+	if !ok {
+		return nil
+	}
+	delete(all, oldName)
+	all[newName] = meta
+
+	return writeJSONFile(dataFilePath(userMetaFileName), all)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// GetUserProfile returns the profile metadata of a user
+func (s *SambaService) GetUserProfile(username string) (*types.UserProfile, error) {
+	s.mu.RLock()
+	account, err := getSambaAccount(username)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, utils.NewNotFoundError("User not found")
+	}
+
+	meta, err := loadUserMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.UserProfile{
+		DisplayName: account.FullName,
+		Email:       meta[username].Email,
+		Department:  meta[username].Department,
+		Notes:       meta[username].Notes,
+	}, nil
+}
+
+// UpdateUserProfile replaces a user's profile metadata (admin)
+func (s *SambaService) UpdateUserProfile(username string, profile *types.UserProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.setDisplayNameInternal(username, profile.DisplayName); err != nil {
+		return err
+	}
+
+	return updateUserMeta(username, func(meta *userMeta) {
+		meta.Email = strings.TrimSpace(profile.Email)
+		meta.Department = strings.TrimSpace(profile.Department)
+		meta.Notes = profile.Notes
+	})
+}
+
+// UpdateOwnProfile updates the profile fields a user may edit themselves
+// Department and notes are managed by admins and left untouched
+func (s *SambaService) UpdateOwnProfile(username string, req *types.UpdateOwnProfileRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.setDisplayNameInternal(username, req.DisplayName); err != nil {
+		return err
+	}
+
+	return updateUserMeta(username, func(meta *userMeta) {
+		meta.Email = strings.TrimSpace(req.Email)
+	})
+}
+
+// setDisplayNameInternal stores a display name as the Samba full name (must hold lock)
+// The full name is also what Windows clients show for the account
+func (s *SambaService) setDisplayNameInternal(username string, displayName string) error {
+	// SECURITY: validate username format to prevent command injection
+	if !isValidUsername(username) {
+		return fmt.Errorf("invalid username")
+	}
+	if !s.UserExists(username) {
+		return utils.NewNotFoundError("User not found")
+	}
+
+	displayName = strings.TrimSpace(displayName)
+	if err := validateDisplayName(displayName); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("pdbedit", "-u", username, "-f", displayName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set display name: %v, output: %s", err, output)
+	}
+
+	return nil
+}
+
+// validateDisplayName rejects display names that would break pdbedit's line based output
+func validateDisplayName(displayName string) error {
+	if len(displayName) > 64 {
+		return fmt.Errorf("display name must be at most 64 characters")
+	}
+	for _, r := range displayName {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("display name must not contain control characters")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// GetUser returns all details of a single user
+func (s *SambaService) GetUser(username string) (*types.UserDetailResponse, error) {
+	if !isValidUsername(username) {
+		return nil, utils.NewNotFoundError("User not found")
+	}
+
+	s.mu.RLock()
+	account, err := getSambaAccount(username)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, utils.NewNotFoundError("User not found")
+	}
+
+	meta, err := loadUserMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &types.UserDetailResponse{
+		UserResponse:       newUserResponse(account, meta[username]),
+		SID:                account.SID,
+		Domain:             account.Domain,
+		Description:        account.Description,
+		Notes:              meta[username].Notes,
+		PasswordCanChange:  account.PasswordCanChange,
+		PasswordMustChange: account.PasswordMustChange,
+		KickoffTime:        account.KickoffTime,
+		LastBadPassword:    account.LastBadPassword,
+		LogonHours:         account.LogonHours,
+	}
+
+	if info, err := os.Stat(detail.HomeDir); err == nil && info.IsDir() {
+		detail.HomeDirExists = true
+	}
+
+	shares, err := s.ListShares()
+	if err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		if share.Owner == username {
+			detail.OwnedShares++
+		} else if contains(share.SharedWith, username) {
+			detail.GrantedShares++
+		}
+	}
+
+	return detail, nil
+}
+
+// FilterAndSortUsers applies the search, status filter and sort order of a user list query
+func FilterAndSortUsers(users []types.UserResponse, query *types.UserListQuery) []types.UserResponse {
+	searchLower := strings.ToLower(query.Search)
+
+	filtered := []types.UserResponse{}
+	for _, user := range users {
+		if searchLower != "" &&
+			!strings.Contains(strings.ToLower(user.Username), searchLower) &&
+			!strings.Contains(strings.ToLower(user.DisplayName), searchLower) &&
+			!strings.Contains(strings.ToLower(user.Email), searchLower) {
+			continue
+		}
+
+		switch query.Status {
+		case types.UserStatusActive:
+			if user.Disabled || user.Locked {
+				continue
+			}
+		case types.UserStatusDisabled:
+			if !user.Disabled {
+				continue
+			}
+		case types.UserStatusLocked:
+			if !user.Locked {
+				continue
+			}
+		}
+
+		filtered = append(filtered, user)
+	}
+
+	less := userLessFunc(query.SortBy)
+	desc := query.Order == "desc"
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if desc {
+			return less(&filtered[j], &filtered[i])
+		}
+		return less(&filtered[i], &filtered[j])
+	})
+
+	return filtered
+}
+
+// userLessFunc returns the comparison for a sort field (username by default)
+// Ties and missing values fall back to the username so the order is stable across pages
+func userLessFunc(sortBy string) func(a, b *types.UserResponse) bool {
+	byUsername := func(a, b *types.UserResponse) bool {
+		return a.Username < b.Username
+	}
+
+	switch sortBy {
+	case types.UserSortDisplayName:
+		return func(a, b *types.UserResponse) bool {
+			nameA, nameB := strings.ToLower(a.DisplayName), strings.ToLower(b.DisplayName)
+			if nameA != nameB {
+				return nameA < nameB
+			}
+			return byUsername(a, b)
+		}
+	case types.UserSortPasswordLastSet:
+		return func(a, b *types.UserResponse) bool {
+			return timeLess(a.PasswordLastSet, b.PasswordLastSet, byUsername(a, b))
+		}
+	case types.UserSortLogonTime:
+		return func(a, b *types.UserResponse) bool {
+			return timeLess(a.LogonTime, b.LogonTime, byUsername(a, b))
+		}
+	case types.UserSortBadPasswordCount:
+		return func(a, b *types.UserResponse) bool {
+			if a.BadPasswordCount != b.BadPasswordCount {
+				return a.BadPasswordCount < b.BadPasswordCount
+			}
+			return byUsername(a, b)
+		}
+	}
+
+	return byUsername
+}
+
+// timeLess compares optional times, treating nil as the oldest value
+func timeLess(a, b *time.Time, tie bool) bool {
+	switch {
+	case a == nil && b == nil:
+		return tie
+	case a == nil:
+		return true
+	case b == nil:
+		return false
+	case a.Equal(*b):
+		return tie
+	}
+	return a.Before(*b)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/itsHenry35/SambaManager/types"
+)
+
+func TestFilterAndSortUsers(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2026, time.June, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	users := []types.UserResponse{
+		{Username: "dave", DisplayName: "dave", PasswordLastSet: day(3), BadPasswordCount: 1},
+		{Username: "alice", DisplayName: "Zoe Alice", Email: "alice@example.com", PasswordLastSet: day(2), LogonTime: day(5)},
+		{Username: "carol", DisplayName: "Carol", Disabled: true, PasswordLastSet: day(2), LogonTime: day(1), BadPasswordCount: 1},
+		{Username: "bob", Email: "bob@corp.example", Locked: true, BadPasswordCount: 5},
+	}
+
+	tests := []struct {
+		name  string
+		query types.UserListQuery
+		want  []string
+	}{
+		{"default order", types.UserListQuery{}, []string{"alice", "bob", "carol", "dave"}},
+		{"descending", types.UserListQuery{Order: "desc"}, []string{"dave", "carol", "bob", "alice"}},
+		{"search username", types.UserListQuery{PaginationQuery: types.PaginationQuery{Search: "CAR"}}, []string{"carol"}},
+		{"search display name", types.UserListQuery{PaginationQuery: types.PaginationQuery{Search: "zoe"}}, []string{"alice"}},
+		{"search email", types.UserListQuery{PaginationQuery: types.PaginationQuery{Search: "corp"}}, []string{"bob"}},
+		{"search without match", types.UserListQuery{PaginationQuery: types.PaginationQuery{Search: "nobody"}}, []string{}},
+		{"active", types.UserListQuery{Status: types.UserStatusActive}, []string{"alice", "dave"}},
+		{"disabled", types.UserListQuery{Status: types.UserStatusDisabled}, []string{"carol"}},
+		{"locked", types.UserListQuery{Status: types.UserStatusLocked}, []string{"bob"}},
+		{"search and status", types.UserListQuery{PaginationQuery: types.PaginationQuery{Search: "a"}, Status: types.UserStatusActive}, []string{"alice", "dave"}},
+		// Display names compare case-insensitively, empty ones first
+		{"display name", types.UserListQuery{SortBy: types.UserSortDisplayName}, []string{"bob", "carol", "dave", "alice"}},
+		// Unknown times sort as the oldest, equal ones by username
+		{"password last set", types.UserListQuery{SortBy: types.UserSortPasswordLastSet}, []string{"bob", "alice", "carol", "dave"}},
+		{"password last set descending", types.UserListQuery{SortBy: types.UserSortPasswordLastSet, Order: "desc"}, []string{"dave", "carol", "alice", "bob"}},
+		{"logon time", types.UserListQuery{SortBy: types.UserSortLogonTime}, []string{"bob", "dave", "carol", "alice"}},
+		{"bad password count", types.UserListQuery{SortBy: types.UserSortBadPasswordCount}, []string{"alice", "carol", "dave", "bob"}},
+		{"bad password count descending", types.UserListQuery{SortBy: types.UserSortBadPasswordCount, Order: "desc"}, []string{"bob", "dave", "carol", "alice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FilterAndSortUsers(users, &tt.query)
+			got := make([]string, 0, len(result))
+			for _, user := range result {
+				got = append(got, user.Username)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FilterAndSortUsers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Sorting works on a filtered copy, the input keeps its order
+	if users[0].Username != "dave" {
+		t.Error("FilterAndSortUsers reordered its input")
+	}
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// RenameUser renames a user while keeping their password, home directory and shares
+// tdbsam has no rename, so the account is re-imported under the new name with the same
+// password hash and flags. Every step is undone if a later step fails.
+func (s *SambaService) RenameUser(oldName string, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// SECURITY: validate usernames to prevent command injection
+	if !isValidUsername(oldName) || !isValidUsername(newName) {
+		return fmt.Errorf("invalid username: must contain only letters, numbers, underscore, and dash")
+	}
+	if oldName == newName {
+		return fmt.Errorf("new username is the same as the current one")
+	}
+	if IsAdminAccount(newName) {
+		return fmt.Errorf("username '%s' is used by a web administrator", newName)
+	}
+
+	account, err := getSambaAccount(oldName)
+	if err != nil {
+		return utils.NewNotFoundError("User not found")
+	}
+	if s.UserExists(newName) {
+		return fmt.Errorf("user '%s' already exists", newName)
+	}
+
+	oldHome := filepath.Join(config.AppConfig.HomeDir, oldName)
+	newHome := filepath.Join(config.AppConfig.HomeDir, newName)
+	if _, err := os.Lstat(newHome); err == nil {
+		return fmt.Errorf("home directory '%s' already exists: adopt, archive or delete it first", newName)
+	}
+
+	// Export the password hash and flags in smbpasswd format: name:uid:LM:NT:[flags]:LCT-...:
+	cmd := exec.Command("pdbedit", "-L", "-w", "-u", oldName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to read samba account: %v, output: %s", err, output)
+	}
+	oldEntry := strings.TrimSpace(string(output))
+	entryFields := strings.Split(oldEntry, ":")
+	if len(entryFields) < 6 || entryFields[0] != oldName {
+		return fmt.Errorf("unexpected pdbedit output for user %s", oldName)
+	}
+
+	configPath := config.AppConfig.Samba.ConfigPath
+	originalConfig, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read samba config: %v", err)
+	}
+	newConfig, err := s.renameUserInShares(string(originalConfig), oldName, newName)
+	if err != nil {
+		return err
+	}
+
+	// Undo steps run in reverse order when a later step fails
+	var undo []func()
+	rollback := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return err
+	}
+
+	// Unix account in extrausers (files are owned by root, so a new UID doesn't matter)
+	cmd = exec.Command("useradd",
+		"--extrausers",
+		"--no-create-home",
+		"--shell", "/usr/sbin/nologin",
+		"--home-dir", newHome,
+		"--badname",
+		newName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create unix user: %v, output: %s", err, output)
+	}
+	undo = append(undo, func() {
+		_ = exec.Command("userdel", "--extrausers", newName).Run()
+	})
+
+	// Samba account with the same password hash and flags
+	uidOutput, err := exec.Command("id", "-u", newName).Output()
+	if err != nil {
+		return rollback(fmt.Errorf("failed to look up new unix user: %v", err))
+	}
+	entryFields[0] = newName
+	entryFields[1] = strings.TrimSpace(string(uidOutput))
+	if err := importSmbpasswdEntry(strings.Join(entryFields, ":")); err != nil {
+		return rollback(err)
+	}
+	undo = append(undo, func() {
+		_ = exec.Command("pdbedit", "-x", "-u", newName).Run()
+	})
+	if account.FullName != "" {
+		if err := exec.Command("pdbedit", "-u", newName, "-f", account.FullName).Run(); err != nil {
+			log.Printf("Failed to copy full name to renamed user %s: %v", newName, err)
+		}
+	}
+
+	// Home directory
+	if _, err := os.Stat(oldHome); err == nil {
+		if err := os.Rename(oldHome, newHome); err != nil {
+			return rollback(fmt.Errorf("failed to move home directory: %v", err))
+		}
+		undo = append(undo, func() {
+			_ = os.Rename(newHome, oldHome)
+		})
+	}
+
+	// Share sections
+	if err := os.WriteFile(configPath, []byte(newConfig), 0644); err != nil {
+		return rollback(fmt.Errorf("failed to write samba config: %v", err))
+	}
+	undo = append(undo, func() {
+		_ = os.WriteFile(configPath, originalConfig, 0644)
+		_ = ReloadSambaConfig()
+	})
+
+	// Remove the old accounts last, they can be restored from the exported entry
+	cmd = exec.Command("pdbedit", "-x", "-u", oldName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return rollback(fmt.Errorf("failed to delete old samba user: %v, output: %s", err, output))
+	}
+	undo = append(undo, func() {
+		_ = importSmbpasswdEntry(oldEntry)
+		if account.FullName != "" {
+			_ = exec.Command("pdbedit", "-u", oldName, "-f", account.FullName).Run()
+		}
+	})
+
+	cmd = exec.Command("userdel", "--extrausers", oldName)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return rollback(fmt.Errorf("failed to delete old unix user: %v, output: %s", err, output))
+	}
+
+	// Metadata is only moved once the rename can no longer be rolled back
+	if err := renameUserMeta(oldName, newName); err != nil {
+		log.Printf("Failed to move metadata of renamed user %s: %v", newName, err)
+	}
+	if err := renamePasswordHistory(oldName, newName); err != nil {
+		log.Printf("Failed to move password history of renamed user %s: %v", newName, err)
+	}
+	if err := renameSambaAdmin(oldName, newName); err != nil {
+		log.Printf("Failed to move admin roles of renamed user %s: %v", newName, err)
+	}
+	if err := renameTwoFactor(oldName, newName); err != nil {
+		log.Printf("Failed to move two-factor authentication of renamed user %s: %v", newName, err)
+	}
+
+	// Hot reload Samba configuration
+	_ = ReloadSambaConfig()
+
+	return nil
+}
+
+// renameUserInShares rewrites share sections for a renamed user
+// Owned shares get a new "<owner>-share-" section ID and path, grants are renamed in place
+func (s *SambaService) renameUserInShares(content string, oldName string, newName string) (string, error) {
+	shares, err := s.parseSharesFromContent(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse shares: %v", err)
+	}
+
+	sharesToDelete := make(map[string]bool)
+	sharesToUpdate := make(map[string]*types.Share)
+	var renamedSections []string
+
+	for _, share := range shares {
+		sharedWith := make([]string, len(share.SharedWith))
+		for i, username := range share.SharedWith {
+			if username == oldName {
+				username = newName
+			}
+			sharedWith[i] = username
+		}
+
+		if share.Owner == oldName {
+			newID := newName + "-share-" + strings.TrimPrefix(share.ID, oldName+"-share-")
+			if strings.Contains(content, fmt.Sprintf("[%s]", newID)) {
+				return "", fmt.Errorf("share '%s' already exists", newID)
+			}
+			sharesToDelete[share.ID] = true
+			renamedSections = append(renamedSections, buildShareConfig(newID, &types.Share{
+				Owner:      newName,
+				SharedWith: sharedWith,
+				ReadOnly:   share.ReadOnly,
+				Comment:    share.Comment,
+				SubPath:    share.SubPath,
+			}))
+			continue
+		}
+
+		if contains(share.SharedWith, oldName) {
+			sharesToUpdate[share.ID] = &types.Share{
+				Owner:      share.Owner,
+				SharedWith: sharedWith,
+				ReadOnly:   share.ReadOnly,
+				Comment:    share.Comment,
+				SubPath:    share.SubPath,
+			}
+		}
+	}
+
+	newContent, err := s.modifySharesInContent(content, sharesToDelete, sharesToUpdate)
+	if err != nil {
+		return "", fmt.Errorf("failed to update shares: %v", err)
+	}
+
+	if len(renamedSections) > 0 && !strings.HasSuffix(newContent, "\n") {
+		newContent += "\n"
+	}
+	return newContent + strings.Join(renamedSections, ""), nil
+}
+
+// importSmbpasswdEntry imports one smbpasswd-format line into the Samba user database
+func importSmbpasswdEntry(entry string) error {
+	// The file holds a password hash, keep it in the private data directory
+	if err := os.MkdirAll(config.AppConfig.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	file, err := os.CreateTemp(config.AppConfig.DataDir, "import-*.smbpasswd")
+	if err != nil {
+		return fmt.Errorf("failed to create import file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString(entry + "\n")
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write import file: %v", err)
+	}
+
+	cmd := exec.Command("pdbedit", "-i", "smbpasswd:"+file.Name())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to import samba user: %v, output: %s", err, output)
+	}
+
+	return nil
+}
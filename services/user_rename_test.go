@@ -0,0 +1,196 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/itsHenry35/SambaManager/config"
+	"github.com/itsHenry35/SambaManager/types"
+)
+
+// fakeAccountTools stands in for pdbedit, useradd, userdel, id and smbcontrol
+// Every change is appended to $FAKE_LOG, the command matching the $FAKE_FAIL pattern fails
+const fakeAccountTools = `#!/bin/sh
+cmd="$(basename "$0") $*"
+case "$cmd" in
+"pdbedit -L -w -u alice") echo "alice:1000:XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX:0123456789ABCDEF0123456789ABCDEF:[U          ]:LCT-6500000:"; exit 0 ;;
+"pdbedit -L -v -u alice") printf 'Unix username:        alice\nAccount Flags:        [U          ]\nFull Name:            Alice Example\n'; exit 0 ;;
+"pdbedit -L "*) exit 1 ;;
+"id -u alice2") echo 1001; exit 0 ;;
+"pdbedit -i "*) cmd="pdbedit -i $(cat "${2#smbpasswd:}")" ;;
+esac
+echo "$cmd" >> "$FAKE_LOG"
+case "$cmd" in
+$FAKE_FAIL) exit 1 ;;
+esac
+exit 0
+`
+
+// setupRename installs the fake tools and a config with a share owned by and a share granted to alice
+func setupRename(t *testing.T, fail string) (logPath string, smbConf string) {
+	t.Helper()
+
+	bin := t.TempDir()
+	for _, name := range []string{"pdbedit", "useradd", "userdel", "id", "smbcontrol"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(fakeAccountTools), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logPath = filepath.Join(t.TempDir(), "commands.log")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_LOG", logPath)
+	t.Setenv("FAKE_FAIL", fail)
+
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig = &config.Config{DataDir: t.TempDir(), HomeDir: t.TempDir()}
+	if err := os.Mkdir(filepath.Join(config.AppConfig.HomeDir, "alice"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	smbConf = "[global]\n   workgroup = WORKGROUP\n" +
+		buildShareConfig("alice-share-docs", &types.Share{Owner: "alice", SharedWith: []string{"alice", "bob"}}) +
+		buildShareConfig("bob-share-music", &types.Share{Owner: "bob", SharedWith: []string{"bob", "alice"}, ReadOnly: true})
+	config.AppConfig.Samba.ConfigPath = filepath.Join(t.TempDir(), "smb.conf")
+	if err := os.WriteFile(config.AppConfig.Samba.ConfigPath, []byte(smbConf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return logPath, smbConf
+}
+
+func readCommandLog(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+const (
+	renameUseradd    = "useradd --extrausers --no-create-home --shell /usr/sbin/nologin --home-dir %s --badname alice2"
+	renameImportNew  = "pdbedit -i alice2:1001:XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX:0123456789ABCDEF0123456789ABCDEF:[U          ]:LCT-6500000:"
+	renameImportOld  = "pdbedit -i alice:1000:XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX:0123456789ABCDEF0123456789ABCDEF:[U          ]:LCT-6500000:"
+	renameFullName   = "pdbedit -u alice2 -f Alice Example"
+	renameDeleteOld  = "pdbedit -x -u alice"
+	renameUserdelOld = "userdel --extrausers alice"
+)
+
+func TestRenameUserRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		fail string   // Pattern of the failing command
+		undo []string // Commands expected after the failing one, in this order
+	}{
+		{
+			name: "unix account",
+			fail: "useradd *",
+			undo: []string{},
+		},
+		{
+			name: "samba account",
+			fail: "pdbedit -i *",
+			undo: []string{"userdel --extrausers alice2"},
+		},
+		{
+			name: "old samba account",
+			fail: renameDeleteOld,
+			undo: []string{
+				"smbcontrol smbd reload-config",
+				"pdbedit -x -u alice2",
+				"userdel --extrausers alice2",
+			},
+		},
+		{
+			name: "old unix account",
+			fail: renameUserdelOld,
+			undo: []string{
+				renameImportOld,
+				"pdbedit -u alice -f Alice Example",
+				"smbcontrol smbd reload-config",
+				"pdbedit -x -u alice2",
+				"userdel --extrausers alice2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath, smbConf := setupRename(t, tt.fail)
+
+			if err := NewSambaService().RenameUser("alice", "alice2"); err == nil {
+				t.Fatal("RenameUser succeeded, want an error")
+			}
+
+			commands := readCommandLog(t, logPath)
+			failed := slices.IndexFunc(commands, func(cmd string) bool {
+				// Patterns are a full command or a command prefix followed by "*"
+				if prefix, ok := strings.CutSuffix(tt.fail, "*"); ok {
+					return strings.HasPrefix(cmd, prefix)
+				}
+				return cmd == tt.fail
+			})
+			if failed < 0 {
+				t.Fatalf("failing command %q not run: %q", tt.fail, commands)
+			}
+			if got := commands[failed+1:]; !slices.Equal(got, tt.undo) {
+				t.Errorf("undo commands = %q, want %q", got, tt.undo)
+			}
+
+			home := config.AppConfig.HomeDir
+			if _, err := os.Stat(filepath.Join(home, "alice")); err != nil {
+				t.Errorf("old home directory not restored: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(home, "alice2")); !os.IsNotExist(err) {
+				t.Errorf("new home directory left behind: %v", err)
+			}
+			if data, _ := os.ReadFile(config.AppConfig.Samba.ConfigPath); string(data) != smbConf {
+				t.Errorf("samba config not restored:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestRenameUser(t *testing.T) {
+	logPath, _ := setupRename(t, "")
+
+	if err := NewSambaService().RenameUser("alice", "alice2"); err != nil {
+		t.Fatalf("RenameUser failed: %v", err)
+	}
+
+	home := config.AppConfig.HomeDir
+	want := []string{
+		fmt.Sprintf(renameUseradd, filepath.Join(home, "alice2")),
+		renameImportNew,
+		renameFullName,
+		renameDeleteOld,
+		renameUserdelOld,
+		"smbcontrol smbd reload-config",
+	}
+	if got := readCommandLog(t, logPath); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, "alice2")); err != nil {
+		t.Errorf("home directory not moved: %v", err)
+	}
+	data, err := os.ReadFile(config.AppConfig.Samba.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{"[alice2-share-docs]", "valid users = alice2 bob", "valid users = bob alice2"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("samba config misses %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "[alice-share-docs]") {
+		t.Errorf("samba config still has the old share section:\n%s", content)
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/itsHenry35/SambaManager/utils"
+)
+
+// SetUserEnabled disables or re-enables a Samba account without touching its data
+// Re-enabling also clears an automatic lockout caused by bad passwords
+func (s *SambaService) SetUserEnabled(username string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// SECURITY: validate username format to prevent command injection
+	if !isValidUsername(username) {
+		return fmt.Errorf("invalid username")
+	}
+	if !s.UserExists(username) {
+		return utils.NewNotFoundError("User not found")
+	}
+
+	flag := "-d"
+	if enabled {
+		flag = "-e"
+	}
+	cmd := exec.Command("smbpasswd", flag, username)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to update samba user: %v, output: %s", err, output)
+	}
+
+	if enabled {
+		cmd = exec.Command("pdbedit", "-z", "-u", username)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to reset bad password count: %v, output: %s", err, output)
+		}
+	}
+
+	return nil
+}
+
+// GetUserStatus reports whether a Samba account exists and whether it may log in
+func GetUserStatus(username string) (exists bool, active bool) {
+	account, err := getSambaAccount(username)
+	if err != nil {
+		return false, false
+	}
+	return true, !account.Disabled() && !account.Locked()
+}
@@ -0,0 +1,55 @@
+package types
+
+import "time"
+
+// Admin roles, a full admin may do everything
+const (
+	AdminRoleFull         = "admin"
+	AdminRoleUserManager  = "user-manager"  // Users, orphaned home directories and password resets
+	AdminRoleShareManager = "share-manager" // Shares and public download links
+	AdminRoleAuditor      = "auditor"       // Read-only access to all admin pages
+)
+
+// AdminAccount represents a stored web administrator
+type AdminAccount struct {
+	Username          string    `json:"username"`
+	PasswordHash      string    `json:"password_hash"` // bcrypt hash
+	Roles             []string  `json:"roles"`         // Empty for accounts created before roles existed, meaning full admin
+	CreatedAt         time.Time `json:"created_at"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// SambaAdminGrant represents admin roles granted to an existing Samba user
+type SambaAdminGrant struct {
+	Roles     []string  `json:"roles"`
+	GrantedBy string    `json:"granted_by"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// AdminResponse represents a web administrator returned to client
+type AdminResponse struct {
+	Username          string     `json:"username"`
+	Roles             []string   `json:"roles"`
+	SambaUser         bool       `json:"samba_user"` // Promoted Samba user, logs in with the Samba password
+	CreatedAt         time.Time  `json:"created_at"` // When the account was created or the Samba user was promoted
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	GrantedBy         string     `json:"granted_by,omitempty"`
+}
+
+// CreateAdminRequest represents a request to add a web administrator
+type CreateAdminRequest struct {
+	Username string   `json:"username" binding:"required"`
+	Password string   `json:"password" binding:"required,min=8,max=72"` // bcrypt only uses the first 72 bytes
+	Roles    []string `json:"roles" binding:"required,min=1,dive,oneof=admin user-manager share-manager auditor"`
+}
+
+// UpdateAdminRolesRequest represents a request to change the roles of a web administrator
+type UpdateAdminRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,min=1,dive,oneof=admin user-manager share-manager auditor"`
+}
+
+// ChangeAdminPasswordRequest represents a request to change a web administrator's password
+type ChangeAdminPasswordRequest struct {
+	CurrentPassword string `json:"current_password"` // Required when changing your own password
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
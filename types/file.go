@@ -0,0 +1,38 @@
+package types
+
+import "time"
+
+// FileEntry represents a file or directory inside a browsable root
+type FileEntry struct {
+	Name    string    `json:"name"`     // Base name of the entry
+	Path    string    `json:"path"`     // Path relative to the browsed root (home or share)
+	IsDir   bool      `json:"is_dir"`   // Whether the entry is a directory
+	Size    int64     `json:"size"`     // Size in bytes (0 for directories)
+	ModTime time.Time `json:"mod_time"` // Last modification time
+}
+
+// DirectoryListingResponse represents the contents of a directory
+type DirectoryListingResponse struct {
+	Path     string      `json:"path"`      // Path relative to the browsed root
+	ReadOnly bool        `json:"read_only"` // Whether write operations are refused
+	Entries  []FileEntry `json:"entries"`
+}
+
+// CreateDirectoryRequest represents a request to create a directory
+type CreateDirectoryRequest struct {
+	Path string `json:"path" binding:"required"` // Path of the new directory relative to the root
+}
+
+// RenamePathRequest represents a request to rename or move a file or directory
+type RenamePathRequest struct {
+	From string `json:"from" binding:"required"` // Current path relative to the root
+	To   string `json:"to" binding:"required"`   // New path relative to the root
+}
+
+// SharedWithMeResponse represents a share that has been granted to the current user
+type SharedWithMeResponse struct {
+	ID       string `json:"id"`        // Share ID
+	Owner    string `json:"owner"`     // Owner username
+	ReadOnly bool   `json:"read_only"` // Whether the share is read-only
+	Comment  string `json:"comment"`   // Share description
+}
@@ -0,0 +1,51 @@
+package types
+
+import "time"
+
+// DownloadLink represents a stored public download link
+type DownloadLink struct {
+	ID           string     `json:"id"`                      // Random link identifier
+	Owner        string     `json:"owner"`                   // Username that created the link
+	Path         string     `json:"path"`                    // Path relative to the owner's home directory
+	IsDir        bool       `json:"is_dir"`                  // Whether the link points to a folder (served as zip)
+	PasswordHash string     `json:"password_hash,omitempty"` // bcrypt hash of the optional link password
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // Nil means the link never expires
+	Downloads    int        `json:"downloads"`            // Number of completed download requests
+}
+
+// DownloadLinkResponse represents link information returned to its owner or an admin
+type DownloadLinkResponse struct {
+	ID          string     `json:"id"`
+	Owner       string     `json:"owner"`
+	Path        string     `json:"path"`
+	IsDir       bool       `json:"is_dir"`
+	HasPassword bool       `json:"has_password"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Downloads   int        `json:"downloads"`
+	Token       string     `json:"token"` // Signed token to put in the public URL
+	URL         string     `json:"url"`   // Public download URL path
+}
+
+// CreateDownloadLinkRequest represents a request to create a public download link
+type CreateDownloadLinkRequest struct {
+	Path           string `json:"path" binding:"required"`                    // Path relative to the user's home directory
+	Password       string `json:"password"`                                   // Optional password required to download
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=0"` // 0 means no expiry (unless capped by policy)
+}
+
+// PublicLinkInfo represents what an anonymous visitor can see about a link
+type PublicLinkInfo struct {
+	Name             string     `json:"name"`
+	IsDir            bool       `json:"is_dir"`
+	Size             int64      `json:"size"` // File size in bytes (0 for folders)
+	PasswordRequired bool       `json:"password_required"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+// DownloadLinkPolicy represents the admin policy for public download links
+type DownloadLinkPolicy struct {
+	Enabled          bool `json:"enabled"`                                      // Whether users may create and use links
+	MaxLifetimeHours int  `json:"max_lifetime_hours" binding:"omitempty,min=0"` // 0 means unlimited lifetime
+}
@@ -0,0 +1,44 @@
+package types
+
+import "time"
+
+// Kinds of login throttle entries
+const (
+	LoginThrottleUsername = "username"
+	LoginThrottleIP       = "ip"
+	LoginThrottleLink     = "link" // Password of a public download link
+)
+
+// LoginThrottle represents the failed login state of a username, public link or client IP address
+type LoginThrottle struct {
+	Kind         string    `json:"kind"`  // "username", "link" or "ip"
+	Value        string    `json:"value"` // The username, link ID or IP address
+	Failures     int       `json:"failures"`
+	FirstFailure time.Time `json:"first_failure"`
+	LastFailure  time.Time `json:"last_failure"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Lockouts     int       `json:"lockouts"`   // Consecutive lockouts, each one lasts twice as long
+	LockedOut    bool      `json:"locked_out"` // Blocked by a lockout rather than the backoff delay
+}
+
+// LoginFailure is an audit record of a failed login attempt
+type LoginFailure struct {
+	Time      time.Time `json:"time"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+}
+
+// LoginFailureQuery represents filters for the failed login audit log
+type LoginFailureQuery struct {
+	Username string `form:"username"`
+	IP       string `form:"ip"`
+	Limit    int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// UnlockLoginRequest represents a request to clear the failed logins of a username, link or IP address
+type UnlockLoginRequest struct {
+	Kind  string `json:"kind" binding:"required,oneof=username link ip"`
+	Value string `json:"value" binding:"required"`
+}
@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// Notification represents a message for administrators (e.g. a summary of automatic actions)
+type Notification struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+	Read      bool      `json:"read"`
+}
@@ -0,0 +1,11 @@
+package types
+
+// OIDCConfigResponse tells the login page whether single sign-on is offered
+type OIDCConfigResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// OIDCExchangeRequest exchanges the one-time code from the single sign-on redirect for tokens
+type OIDCExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
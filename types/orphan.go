@@ -1,8 +1,120 @@
 package types
 
+import "time"
+
+// Size calculation states of an orphaned directory
+const (
+	SizeStatusReady       = "ready"       // Size and file count are up to date
+	SizeStatusCalculating = "calculating" // Calculation is queued or running, values may be stale or partial
+)
+
 // OrphanedDirectory represents a home directory without a corresponding user
 type OrphanedDirectory struct {
-	Name string `json:"name"` // Directory name (username)
-	Path string `json:"path"` // Full path to directory
-	Size int64  `json:"size"` // Total size in bytes
+	Name         string     `json:"name"`                    // Directory name (username)
+	Path         string     `json:"path"`                    // Full path to directory
+	Size         int64      `json:"size"`                    // Total size in bytes
+	FileCount    int64      `json:"file_count"`              // Number of files
+	LastModified time.Time  `json:"last_modified"`           // Modification time of the directory
+	SizeStatus   string     `json:"size_status"`             // "ready" or "calculating"
+	CalculatedAt *time.Time `json:"calculated_at,omitempty"` // When the size was last calculated
+	FirstSeenAt  *time.Time `json:"first_seen_at,omitempty"` // When the directory was first detected as orphaned
+}
+
+// OrphanSizeJobStatus reports the progress of background size calculation
+type OrphanSizeJobStatus struct {
+	Running      bool   `json:"running"`       // Whether a directory is being scanned
+	Current      string `json:"current"`       // Directory currently being scanned
+	CurrentFiles int64  `json:"current_files"` // Files scanned so far in the current directory
+	CurrentBytes int64  `json:"current_bytes"` // Bytes counted so far in the current directory
+	Pending      int    `json:"pending"`       // Directories waiting to be scanned
+}
+
+// OrphanArchive represents an archived orphaned home directory
+type OrphanArchive struct {
+	ID           string    `json:"id"`            // Archive identifier (original name and timestamp)
+	OriginalName string    `json:"original_name"` // Name of the archived directory (former username)
+	FileName     string    `json:"file_name"`     // Archive file name inside the archive directory
+	Format       string    `json:"format"`        // "tar.gz" or "tar.zst"
+	Size         int64     `json:"size"`          // Total size of the original files in bytes
+	ArchiveSize  int64     `json:"archive_size"`  // Size of the compressed archive in bytes
+	CreatedAt    time.Time `json:"created_at"`    // When the directory was archived
+}
+
+// RestoreOrphanArchiveRequest represents a request to restore an archive into a user's home
+type RestoreOrphanArchiveRequest struct {
+	Username   string `json:"username" binding:"required"` // Target user
+	CreateUser bool   `json:"create_user"`                 // Create the user first (requires password)
+	Password   string `json:"password"`                    // Password for the new user
+	SubFolder  string `json:"sub_folder"`                  // Folder inside the home to restore into (empty = home root for new users)
+}
+
+// Orphan adoption modes
+const (
+	AdoptModeRecreate = "recreate" // Recreate the user matching the directory name
+	AdoptModeMerge    = "merge"    // Move the contents into another user's home
+)
+
+// Conflict strategies when merging an orphaned directory
+const (
+	AdoptConflictFail      = "fail"      // Abort without moving anything if any path already exists
+	AdoptConflictSkip      = "skip"      // Keep existing files, leave conflicting ones in the orphaned directory
+	AdoptConflictRename    = "rename"    // Move conflicting files under a new name ("name (1).ext")
+	AdoptConflictOverwrite = "overwrite" // Replace existing files
+)
+
+// AdoptOrphanRequest represents a request to adopt an orphaned home directory
+type AdoptOrphanRequest struct {
+	Mode       string `json:"mode" binding:"required,oneof=recreate merge"`
+	Password   string `json:"password"`    // Password for the recreated user (recreate mode)
+	TargetUser string `json:"target_user"` // Existing user receiving the files (merge mode)
+	SubFolder  string `json:"sub_folder"`  // Folder in the target home (merge mode, defaults to the orphan name)
+	OnConflict string `json:"on_conflict" binding:"omitempty,oneof=fail skip rename overwrite"`
+}
+
+// AdoptOrphanResult reports what an adoption did
+type AdoptOrphanResult struct {
+	Username string   `json:"username"`          // User now owning the data
+	Path     string   `json:"path"`              // Directory the data ended up in
+	Moved    int      `json:"moved"`             // Number of files and folders moved
+	Renamed  []string `json:"renamed,omitempty"` // Paths moved under a new name
+	Skipped  []string `json:"skipped,omitempty"` // Paths left in the orphaned directory
+}
+
+// Orphan retention action types
+const (
+	RetentionActionArchive       = "archive"        // Archive an orphaned directory
+	RetentionActionDeleteArchive = "delete_archive" // Permanently delete an old archive
+)
+
+// OrphanRetentionPolicy represents the automatic cleanup policy for orphaned home directories
+type OrphanRetentionPolicy struct {
+	Enabled                 bool `json:"enabled"`                                              // Whether the scheduler enforces the policy
+	ArchiveAfterDays        int  `json:"archive_after_days" binding:"omitempty,min=0"`         // Archive orphans this many days after they were first seen (0 = never)
+	DeleteArchivesAfterDays int  `json:"delete_archives_after_days" binding:"omitempty,min=0"` // Delete archives this many days after they were created (0 = never)
+	IntervalHours           int  `json:"interval_hours" binding:"omitempty,min=0"`             // Hours between scheduled runs (0 = default of 24)
+}
+
+// OrphanRetentionAction represents a single action taken (or planned) by a retention run
+type OrphanRetentionAction struct {
+	Type   string    `json:"type"`            // "archive" or "delete_archive"
+	Target string    `json:"target"`          // Orphaned directory name or archive ID
+	Since  time.Time `json:"since"`           // When the orphan was first seen or the archive was created
+	Size   int64     `json:"size"`            // Directory size (last calculated) or archive file size in bytes
+	Error  string    `json:"error,omitempty"` // Why the action failed (runs only)
+}
+
+// OrphanRetentionPreview represents what the next retention run will do
+type OrphanRetentionPreview struct {
+	Enabled   bool                    `json:"enabled"`
+	NextRunAt *time.Time              `json:"next_run_at,omitempty"` // Nil when the policy is disabled
+	Actions   []OrphanRetentionAction `json:"actions"`
+}
+
+// OrphanRetentionRun represents the result of a retention run
+type OrphanRetentionRun struct {
+	Trigger    string                  `json:"trigger"` // "scheduled" or "manual"
+	StartedAt  time.Time               `json:"started_at"`
+	FinishedAt time.Time               `json:"finished_at"`
+	Actions    []OrphanRetentionAction `json:"actions"`
+	Failed     int                     `json:"failed"` // Number of actions that failed
 }
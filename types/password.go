@@ -0,0 +1,62 @@
+package types
+
+import "time"
+
+// PasswordPolicy represents the rules passwords of Samba users must follow
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length" binding:"min=1,max=127"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	RejectUsername   bool `json:"reject_username"`                     // Reject passwords containing the username
+	CheckDictionary  bool `json:"check_dictionary"`                    // Reject passwords listed in the dictionary file
+	HistorySize      int  `json:"history_size" binding:"min=0,max=24"` // Number of previous passwords that can't be reused
+}
+
+// PasswordPolicyResponse represents the password policy as shown to forms
+type PasswordPolicyResponse struct {
+	PasswordPolicy
+	DictionaryAvailable bool `json:"dictionary_available"` // Whether the dictionary file could be loaded
+}
+
+// PasswordReset represents a stored one-time password reset token
+type PasswordReset struct {
+	TokenHash string    `json:"token_hash"` // SHA-256 of the token, the token itself is never stored
+	Username  string    `json:"username"`
+	SID       string    `json:"sid"` // Account SID at issue time, so a recreated user can't be reset with an old token
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreatePasswordResetRequest represents a request to issue a password reset token
+type CreatePasswordResetRequest struct {
+	LifetimeMinutes int `json:"lifetime_minutes" binding:"omitempty,min=5,max=10080"` // Defaults to 60 minutes
+}
+
+// PasswordResetResponse represents a newly issued password reset token (only shown once)
+type PasswordResetResponse struct {
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PasswordResetInfo represents what the holder of a reset token can see before using it
+type PasswordResetInfo struct {
+	Username  string                 `json:"username"`
+	ExpiresAt time.Time              `json:"expires_at"`
+	Policy    PasswordPolicyResponse `json:"policy"`
+}
+
+// CompletePasswordResetRequest represents a request to set a new password with a reset token
+type CompletePasswordResetRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// PasswordResetTokenRequest represents a request carrying only a reset token
+// Tokens are sent in the body rather than the URL to keep them out of access logs
+type PasswordResetTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
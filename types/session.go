@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// Session represents a signed-in web session (one per issued token)
+type Session struct {
+	ID         string    `json:"id"`
+	Username   string    `json:"username"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SessionResponse represents a session returned to client
+type SessionResponse struct {
+	Session
+	Current bool `json:"current"` // The session making the request
+}
+
+// RevokeSessionsRequest represents a request to sign out all sessions of a user
+type RevokeSessionsRequest struct {
+	Username string `json:"username" binding:"required"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+// TwoFactorPolicy controls which accounts must use two-factor authentication
+type TwoFactorPolicy struct {
+	RequireForAdmins bool `json:"require_for_admins"`
+	RequireForUsers  bool `json:"require_for_users"`
+}
+
+// TwoFactorStatus represents the two-factor state of the current account
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Required          bool       `json:"required"` // Required by the policy, can't be turned off
+	ConfirmedAt       *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+	SetupPending      bool       `json:"setup_pending"` // A secret was generated but not confirmed yet
+}
+
+// TwoFactorSetupResponse contains a new TOTP secret to add to an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI for authenticator apps
+	QRCode          string `json:"qr_code"`          // The provisioning URI as a PNG data URI
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorRecoveryCodesResponse contains recovery codes, shown only once
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorLoginRequest represents the second step of a login
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // TOTP or recovery code
+}
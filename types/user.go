@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // User represents a system user
 type User struct {
 	Username string `json:"username" binding:"required"`
@@ -8,24 +10,102 @@ type User struct {
 
 // UserResponse represents user information returned to client
 type UserResponse struct {
-	Username string `json:"username"`
-	HomeDir  string `json:"home_dir"`
+	Username         string     `json:"username"`
+	DisplayName      string     `json:"display_name,omitempty"` // Stored as the Samba full name
+	Email            string     `json:"email,omitempty"`
+	Department       string     `json:"department,omitempty"`
+	HomeDir          string     `json:"home_dir"`
+	Disabled         bool       `json:"disabled"`                    // Account disabled by an admin (smbpasswd -d)
+	Locked           bool       `json:"locked"`                      // Account locked out after bad passwords
+	AccountFlags     string     `json:"account_flags"`               // Raw Samba account flags, e.g. "DU"
+	PasswordLastSet  *time.Time `json:"password_last_set,omitempty"` // Nil if unknown
+	LogonTime        *time.Time `json:"logon_time,omitempty"`        // Last logon (nil if never recorded)
+	LogoffTime       *time.Time `json:"logoff_time,omitempty"`       // Last logoff (nil if never recorded)
+	BadPasswordCount int        `json:"bad_password_count"`          // Failed logons since the last success
+
+	MustChangePassword bool       `json:"must_change_password"`            // Set by an admin, cleared by the next password change
+	PasswordMaxAgeDays int        `json:"password_max_age_days,omitempty"` // 0 means the password never expires
+	PasswordExpiresAt  *time.Time `json:"password_expires_at,omitempty"`   // Nil if the password never expires
+	PasswordExpired    bool       `json:"password_expired"`                // The user has to change their password at the next login
+}
+
+// UserProfile represents the descriptive metadata of a user
+type UserProfile struct {
+	DisplayName string `json:"display_name" binding:"max=64"`
+	Email       string `json:"email" binding:"omitempty,email,max=254"`
+	Department  string `json:"department" binding:"max=64"`
+	Notes       string `json:"notes" binding:"max=2000"` // Only visible to and editable by admins
+}
+
+// UpdateOwnProfileRequest represents the profile fields users may edit themselves
+type UpdateOwnProfileRequest struct {
+	DisplayName string `json:"display_name" binding:"max=64"`
+	Email       string `json:"email" binding:"omitempty,email,max=254"`
+}
+
+// UserDetailResponse represents all details of a single user
+type UserDetailResponse struct {
+	UserResponse
+	SID                string     `json:"sid"`
+	Domain             string     `json:"domain"`
+	Description        string     `json:"description,omitempty"`
+	Notes              string     `json:"notes,omitempty"` // Admin notes
+	PasswordCanChange  *time.Time `json:"password_can_change,omitempty"`
+	PasswordMustChange *time.Time `json:"password_must_change,omitempty"` // Nil means never
+	KickoffTime        *time.Time `json:"kickoff_time,omitempty"`         // Nil means never
+	LastBadPassword    *time.Time `json:"last_bad_password,omitempty"`
+	LogonHours         string     `json:"logon_hours,omitempty"` // Hex bitmap of allowed logon hours
+	HomeDirExists      bool       `json:"home_dir_exists"`
+	OwnedShares        int        `json:"owned_shares"`   // Shares owned by the user
+	GrantedShares      int        `json:"granted_shares"` // Shares of other users the user can access
+}
+
+// User list sort fields
+const (
+	UserSortUsername         = "username"
+	UserSortDisplayName      = "display_name"
+	UserSortPasswordLastSet  = "password_last_set"
+	UserSortLogonTime        = "logon_time"
+	UserSortBadPasswordCount = "bad_password_count"
+)
+
+// User list status filters
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusLocked   = "locked"
+)
+
+// UserListQuery represents pagination, search, filter and sort parameters of the user list
+type UserListQuery struct {
+	PaginationQuery
+	SortBy string `form:"sort_by" binding:"omitempty,oneof=username display_name password_last_set logon_time bad_password_count"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Status string `form:"status" binding:"omitempty,oneof=active disabled locked"`
 }
 
 // CreateUserRequest represents a request to create a new user
 type CreateUserRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required,min=3"`
+	Username           string `json:"username" binding:"required"`
+	Password           string `json:"password" binding:"required"`
+	MustChangePassword *bool  `json:"must_change_password"` // Defaults to true: the user picks their own password on first login
 }
 
 // UpdateUserRequest represents a request to update user information
 type UpdateUserRequest struct {
-	Password string `json:"password" binding:"required,min=3"`
+	Password string `json:"password" binding:"required"`
 }
 
 // ChangePasswordRequest represents a request to change user password
 type ChangePasswordRequest struct {
-	Password string `json:"password" binding:"required,min=3"`
+	Password           string `json:"password" binding:"required"`
+	MustChangePassword bool   `json:"must_change_password"` // Make the user change the new password at the next login
+}
+
+// PasswordExpiryRequest represents the password expiry settings of a user
+type PasswordExpiryRequest struct {
+	MustChangePassword bool `json:"must_change_password"`
+	MaxAgeDays         int  `json:"max_age_days" binding:"min=0,max=3650"` // 0 means the password never expires
 }
 
 // UserRole represents user role type
@@ -39,10 +119,76 @@ const (
 // ChangeOwnPasswordRequest represents a request for user to change their own password
 type ChangeOwnPasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=3"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// RenameUserRequest represents a request to rename a user
+type RenameUserRequest struct {
+	NewUsername string `json:"new_username" binding:"required"`
 }
 
 // DeleteUserRequest represents options when deleting a user
 type DeleteUserRequest struct {
 	DeleteHomeDir bool `json:"delete_home_dir"` // Whether to delete the user's home directory
 }
+
+// GeneratePassword can be used as the password of an imported user to generate a random one
+const GeneratePassword = "generate"
+
+// UserShareSpec describes a share owned by a user, as used by import and export
+type UserShareSpec struct {
+	Name       string   `json:"name"`        // Optional custom name (alphanumeric only, no symbols)
+	SharedWith []string `json:"shared_with"` // Users with access
+	ReadOnly   bool     `json:"read_only"`
+	SubPath    string   `json:"sub_path,omitempty"` // Optional subdirectory path relative to the owner's home
+}
+
+// ImportUserRow represents one user of a bulk import
+type ImportUserRow struct {
+	Username string          `json:"username"`
+	Password string          `json:"password"` // Password or "generate"
+	Groups   []string        `json:"groups"`   // Not supported, rows setting it are rejected
+	QuotaMB  int64           `json:"quota_mb"` // Not supported, rows setting it are rejected
+	Shares   []UserShareSpec `json:"shares"`   // Shares of the new user's home to create
+
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+	Department  string `json:"department"`
+}
+
+// Import row states
+const (
+	ImportStatusValid   = "valid"   // Row passed validation (dry run)
+	ImportStatusInvalid = "invalid" // Row failed validation, nothing was created
+	ImportStatusCreated = "created" // User (and its shares) were created
+	ImportStatusFailed  = "failed"  // Creation failed
+)
+
+// ImportUserResult represents the outcome of one import row
+type ImportUserResult struct {
+	Row               int      `json:"row"` // 1-based row number (excluding the CSV header)
+	Username          string   `json:"username"`
+	Status            string   `json:"status"`
+	Error             string   `json:"error,omitempty"`
+	GeneratedPassword string   `json:"generated_password,omitempty"` // Only returned once, when the password was generated
+	Shares            []string `json:"shares,omitempty"`             // IDs of the created shares
+}
+
+// ImportUsersResponse represents the outcome of a bulk import
+type ImportUsersResponse struct {
+	DryRun  bool               `json:"dry_run"`
+	Valid   bool               `json:"valid"` // Whether every row passed validation
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Results []ImportUserResult `json:"results"`
+}
+
+// ExportUser represents a user in a bulk export (never includes passwords)
+type ExportUser struct {
+	Username    string          `json:"username"`
+	DisplayName string          `json:"display_name"`
+	Email       string          `json:"email"`
+	Department  string          `json:"department"`
+	HomeDir     string          `json:"home_dir"`
+	Shares      []UserShareSpec `json:"shares"` // Shares owned by the user
+}
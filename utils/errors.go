@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 // NotFoundError represents a not found error
 type NotFoundError struct {
 	Message string
@@ -41,3 +43,32 @@ func (e *UnauthorizedError) Error() string {
 func NewUnauthorizedError(message string) *UnauthorizedError {
 	return &UnauthorizedError{Message: message}
 }
+
+// ValidationError represents invalid input detected by a service
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// NewValidationError creates a new ValidationError
+func NewValidationError(message string) *ValidationError {
+	return &ValidationError{Message: message}
+}
+
+// TooManyRequestsError represents a request refused until the client waits
+type TooManyRequestsError struct {
+	Message    string
+	RetryAfter time.Duration // How long the client has to wait
+}
+
+func (e *TooManyRequestsError) Error() string {
+	return e.Message
+}
+
+// NewTooManyRequestsError creates a new TooManyRequestsError
+func NewTooManyRequestsError(message string, retryAfter time.Duration) *TooManyRequestsError {
+	return &TooManyRequestsError{Message: message, RetryAfter: retryAfter}
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -83,6 +86,15 @@ func ResponseError(c *gin.Context, code int, message string) {
 	})
 }
 
+// ResponseErrorWithData sends an error response that still carries data (e.g. per-item results)
+func ResponseErrorWithData(c *gin.Context, code int, message string, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // ResponseBadRequest sends a bad request error (400)
 func ResponseBadRequest(c *gin.Context, message string) {
 	ResponseError(c, http.StatusBadRequest, message)
@@ -103,7 +115,38 @@ func ResponseNotFound(c *gin.Context, message string) {
 	ResponseError(c, http.StatusNotFound, message)
 }
 
+// ResponseTooManyRequests sends a too many requests error (429)
+func ResponseTooManyRequests(c *gin.Context, message string) {
+	ResponseError(c, http.StatusTooManyRequests, message)
+}
+
 // ResponseInternalServerError sends an internal server error (500)
 func ResponseInternalServerError(c *gin.Context, message string) {
 	ResponseError(c, http.StatusInternalServerError, message)
 }
+
+// ResponseServiceError sends an error response matching the type of a service error
+// NotFoundError, ForbiddenError, UnauthorizedError and ValidationError keep their codes, anything else is a 500
+func ResponseServiceError(c *gin.Context, err error) {
+	var notFoundErr *NotFoundError
+	var forbiddenErr *ForbiddenError
+	var unauthorizedErr *UnauthorizedError
+	var validationErr *ValidationError
+	var tooManyRequestsErr *TooManyRequestsError
+
+	switch {
+	case errors.As(err, &notFoundErr):
+		ResponseNotFound(c, notFoundErr.Error())
+	case errors.As(err, &forbiddenErr):
+		ResponseForbidden(c, forbiddenErr.Error())
+	case errors.As(err, &unauthorizedErr):
+		ResponseUnauthorized(c, unauthorizedErr.Error())
+	case errors.As(err, &validationErr):
+		ResponseBadRequest(c, validationErr.Error())
+	case errors.As(err, &tooManyRequestsErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(tooManyRequestsErr.RetryAfter.Seconds()))))
+		ResponseTooManyRequests(c, tooManyRequestsErr.Error())
+	default:
+		ResponseInternalServerError(c, err.Error())
+	}
+}